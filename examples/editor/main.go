@@ -30,6 +30,7 @@ type example struct {
 	Id            int
 	BillingActive bool
 	Customers     []customer
+	Inventory     map[string]int
 }
 
 func main() {
@@ -48,6 +49,10 @@ func main() {
 				Balance: -5,
 			},
 		},
+		Inventory: map[string]int{
+			"pipes":    40,
+			"fittings": 12,
+		},
 	}
 
 	structeditor.ServeEditor(demoData, "/", http.DefaultServeMux)
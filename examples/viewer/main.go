@@ -30,6 +30,7 @@ type example struct {
 	Id            int
 	BillingActive bool
 	Customers     []customer
+	Inventory     map[string]int
 }
 
 func main() {
@@ -46,6 +47,10 @@ func main() {
 				Balance: -5,
 			},
 		},
+		Inventory: map[string]int{
+			"pipes":    40,
+			"fittings": 12,
+		},
 	}
 
 	structeditor.ServeEditor(demoData, "/", http.DefaultServeMux)
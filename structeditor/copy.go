@@ -0,0 +1,171 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package structeditor
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// Makes deep copies of values
+type copier struct {
+	// The copy made of each reference, so that a pointer, map or slice that
+	// appears more than once in the value being copied (including through
+	// cycles) is copied once, and the copy has the same shape as the original
+	copied map[referenceKey]reflect.Value
+	// If true, struct fields tagged as secret or hidden are left as zero
+	// values in the copy
+	omitProtected bool
+}
+
+// deepCopy returns a copy of v that shares no pointers, maps or slices with
+// it. Channels, functions and unsafe pointers are copied as they are.
+func deepCopy(v reflect.Value) (reflect.Value, error) {
+	return copier{copied: map[referenceKey]reflect.Value{}}.copy(v)
+}
+
+func (c copier) copy(v reflect.Value) (reflect.Value, error) {
+	result := reflect.New(v.Type()).Elem()
+	err := c.copyInto(result, v)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return result, nil
+}
+
+// copyInto sets dst, which must be settable and of the same type as src, to
+// a deep copy of src.
+func (c copier) copyInto(dst, src reflect.Value) error {
+	src, err := readable(src)
+	if err != nil {
+		return err
+	}
+	switch src.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() {
+			return nil
+		}
+		key := referenceKey{src.Pointer(), src.Type(), 0}
+		if existing, ok := c.copied[key]; ok {
+			dst.Set(existing)
+			return nil
+		}
+		target := reflect.New(src.Type().Elem())
+		c.copied[key] = target
+		dst.Set(target)
+		return c.copyInto(target.Elem(), src.Elem())
+	case reflect.Slice:
+		if src.IsNil() {
+			return nil
+		}
+		key := referenceKey{src.Pointer(), src.Type(), src.Len()}
+		if existing, ok := c.copied[key]; ok {
+			dst.Set(existing)
+			return nil
+		}
+		slice := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		c.copied[key] = slice
+		dst.Set(slice)
+		for i := 0; i < src.Len(); i++ {
+			err := c.copyInto(slice.Index(i), src.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			err := c.copyInto(dst.Index(i), src.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if src.IsNil() {
+			return nil
+		}
+		key := referenceKey{src.Pointer(), src.Type(), 0}
+		if existing, ok := c.copied[key]; ok {
+			dst.Set(existing)
+			return nil
+		}
+		m := reflect.MakeMapWithSize(src.Type(), src.Len())
+		c.copied[key] = m
+		dst.Set(m)
+		entries := src.MapRange()
+		for entries.Next() {
+			entryKey := reflect.New(src.Type().Key()).Elem()
+			err := c.copyInto(entryKey, entries.Key())
+			if err != nil {
+				return err
+			}
+			entryValue := reflect.New(src.Type().Elem()).Elem()
+			err = c.copyInto(entryValue, entries.Value())
+			if err != nil {
+				return err
+			}
+			m.SetMapIndex(entryKey, entryValue)
+		}
+	case reflect.Struct:
+		t := src.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if c.omitProtected {
+				tag := parseFieldTag(t.Field(i))
+				if tag.secret || tag.hidden {
+					continue
+				}
+			}
+			field := dst.Field(i)
+			if !field.CanSet() {
+				// Unexported fields are copied too
+				field = reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+			}
+			err := c.copyInto(field, src.Field(i))
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Interface:
+		if src.IsNil() {
+			return nil
+		}
+		contents := reflect.New(src.Elem().Type()).Elem()
+		err := c.copyInto(contents, src.Elem())
+		if err != nil {
+			return err
+		}
+		dst.Set(contents)
+	default:
+		dst.Set(src)
+	}
+	return nil
+}
+
+// readable returns a form of v that can be copied: values obtained from
+// unexported fields are read through their address, and structs and arrays
+// are made addressable so that their own unexported fields can be too.
+func readable(v reflect.Value) (reflect.Value, error) {
+	if !v.CanInterface() {
+		if !v.CanAddr() {
+			return reflect.Value{}, newError(ErrReadOnly, "Unable to copy unaddressable %v obtained from unexported field", v.Type())
+		}
+		v = reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+	}
+	if (v.Kind() == reflect.Struct || v.Kind() == reflect.Array) && !v.CanAddr() {
+		addressable := reflect.New(v.Type()).Elem()
+		addressable.Set(v)
+		v = addressable
+	}
+	return v, nil
+}
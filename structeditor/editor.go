@@ -15,15 +15,22 @@
 package structeditor
 
 import (
+	"html/template"
+	"io"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 )
 
 type Editor interface {
 	// Render the HTML for the editor UI
 	Render() (string, error)
+	// Write the HTML for the editor UI as it is rendered
+	RenderTo(w io.Writer) error
 	// Run the specified operator on the data
-	// referenced by the path.
+	// referenced by the path. Fails with ErrReadOnly
+	// unless the state is a pointer.
 	Mutate(path string, operator Operator) error
 	// Create an operator described by the query params
 	// in a URL
@@ -33,41 +40,133 @@ type Editor interface {
 	// HTTP request handler to render mutation requests generated by the
 	// viewer
 	MutateHandler(w http.ResponseWriter, r *http.Request)
+	// HTTP request handler to return the state as JSON.
+	JSONViewHandler(w http.ResponseWriter, r *http.Request)
+	// HTTP request handler to apply a batch of mutations described by JSON.
+	JSONMutateHandler(w http.ResponseWriter, r *http.Request)
+	// HTTP request handler to download a snapshot of the state.
+	ExportHandler(w http.ResponseWriter, r *http.Request)
+	// HTTP request handler to replace the state with an uploaded snapshot.
+	ImportHandler(w http.ResponseWriter, r *http.Request)
 }
 
 type editor struct {
 	state     interface{}
 	mutateUrl string
+	// Guards state; nil if the state is not guarded.
+	lock sync.Locker
+	// If true, the state is never mutated, even if it is a pointer.
+	readOnly bool
+	// Title of the rendered page
+	title string
+	// Maximum depth of nested composite values to render; 0 for no limit.
+	maxDepth int
+	// Number of elements of an array or slice to render at once; 0 for no
+	// limit.
+	pageSize int
+	// Called before each mutation; a non-nil error cancels the mutation.
+	beforeMutate func(path string, operator Operator) error
+	// Called after each successful mutation.
+	afterMutate func(path string, operator Operator)
+	// Wraps the handlers registered by ServeEditor.
+	handlerWrapper func(http.Handler) http.Handler
+	// Template for the page wrapping the rendered state.
+	pageTemplate *template.Template
+	// If true, unexported struct fields can be mutated.
+	editUnexported bool
 }
 
 // NewEditor creates a new editor instance wrapping the specified state.  If
 // state is a pointer, it can be mutated; if not a pointer, it can be viewed but
 // the UI will not offer mutation tools. The mutatePath parameter provides
 // the path to which requests to change the state are sent.
-func NewEditor(state interface{}, mutatePath string) Editor {
-	return &editor{
-		state:     state,
-		mutateUrl: mutatePath,
+func NewEditor(state interface{}, mutatePath string, options ...Option) Editor {
+	return newEditor(state, mutatePath, options)
+}
+
+func newEditor(state interface{}, mutatePath string, options []Option) *editor {
+	e := &editor{
+		state:        state,
+		mutateUrl:    mutatePath,
+		lock:         &sync.RWMutex{},
+		title:        "Struct Editor",
+		pageSize:     defaultPageSize,
+		pageTemplate: defaultPageTemplate,
+	}
+	for _, option := range options {
+		option(e)
+	}
+	return e
+}
+
+// readerLocker is implemented by locks (such as sync.RWMutex) that can be
+// shared by readers.
+type readerLocker interface {
+	sync.Locker
+	RLock()
+	RUnlock()
+}
+
+// lockForRead takes the state lock for reading and returns the function that
+// releases it.
+func (e *editor) lockForRead() func() {
+	switch lock := e.lock.(type) {
+	case nil:
+		return func() {}
+	case readerLocker:
+		lock.RLock()
+		return lock.RUnlock
+	default:
+		lock.Lock()
+		return lock.Unlock
 	}
 }
 
+// lockForWrite takes the state lock for writing and returns the function that
+// releases it.
+func (e *editor) lockForWrite() func() {
+	if e.lock == nil {
+		return func() {}
+	}
+	e.lock.Lock()
+	return e.lock.Unlock
+}
+
 // ServeEditor creates a new editor for the specified state and configures it to
 // be served at the specified URL (and a "url/mutate" path for edits to the
-// state). The specified serveMux will have paths added to it. As with NewEditor,
-// if state is a pointer, it can be mutated; if not, mutation tools are not
-// shown in the UI.
+// state, unless overridden by WithMutatePath). The JSON API is served at
+// "url/json" (state) and "url/json/mutate" (batched edits). The specified
+// serveMux will have paths added to it. As with NewEditor, if state is a
+// pointer, it can be mutated; if not, mutation tools are not shown in the UI.
 //
 // WARNING: The URLs served by this service expose internal workings of your
 // server and requests are not authenticated / authorized. See "Security Notice"
 // in README.md for details. If you need authentication / authorization, you
-// should wrap the ViewHandler and MutateHandler in the Editor interface in
-// your own auth validation and serve your own endpoints.
-func ServeEditor(state interface{}, path string, serveMux *http.ServeMux) {
-	mutationPath := path + "/mutate"
-	if mutationPath == "//mutate" {
-		mutationPath = "/mutate"
+// should use WithHandlerWrapper to wrap the handlers in your own auth
+// validation, or wrap the handlers in the Editor interface and serve your own
+// endpoints.
+func ServeEditor(state interface{}, path string, serveMux *http.ServeMux, options ...Option) {
+	editor := newEditor(state, subPath(path, "mutate"), options)
+	serveMux.Handle(path, editor.wrapHandler(editor.ViewHandler))
+	serveMux.Handle(editor.mutateUrl, editor.wrapHandler(editor.MutateHandler))
+	serveMux.Handle(subPath(path, "json"), editor.wrapHandler(editor.JSONViewHandler))
+	serveMux.Handle(subPath(path, "json/mutate"), editor.wrapHandler(editor.JSONMutateHandler))
+	serveMux.Handle(subPath(path, "export"), editor.wrapHandler(editor.ExportHandler))
+	serveMux.Handle(subPath(path, "import"), editor.wrapHandler(editor.ImportHandler))
+}
+
+// subPath returns the URL path for the named endpoint beneath path.
+func subPath(path, name string) string {
+	if strings.HasSuffix(path, "/") {
+		return path + name
+	}
+	return path + "/" + name
+}
+
+// wrapHandler applies the editor's handler wrapper (if any) to a handler.
+func (e *editor) wrapHandler(handler http.HandlerFunc) http.Handler {
+	if e.handlerWrapper == nil {
+		return handler
 	}
-	editor := NewEditor(state, mutationPath)
-	serveMux.HandleFunc(path, editor.ViewHandler)
-	serveMux.HandleFunc(mutationPath, editor.MutateHandler)
+	return e.handlerWrapper(handler)
 }
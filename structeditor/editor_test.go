@@ -0,0 +1,261 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package structeditor
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type liveState struct {
+	Counter int
+	Names   []string
+	Scores  map[string]int
+}
+
+// Exercises rendering and mutation against a state that is also being
+// changed by other goroutines. Run with -race to detect unguarded access.
+func runConcurrently(t *testing.T, e Editor, lock sync.Locker, data *liveState) {
+	const iterations = 50
+	var wg sync.WaitGroup
+
+	wg.Add(4)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			if _, err := e.Render(); err != nil {
+				t.Error("Rendering error:", err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			if err := e.Mutate("Counter", OperatorSet(fmt.Sprint(i))); err != nil {
+				t.Error("Mutation error:", err)
+			}
+			if err := e.Mutate("Names", OperatorGrow()); err != nil {
+				t.Error("Mutation error:", err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			req := httptest.NewRequest("POST", "/mutate?operator=insert&path=Scores&key="+fmt.Sprint(i), nil)
+			e.MutateHandler(httptest.NewRecorder(), req)
+			e.ViewHandler(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			lock.Lock()
+			data.Counter++
+			data.Names = append(data.Names, "x")
+			lock.Unlock()
+		}
+	}()
+	wg.Wait()
+
+	if len(data.Names) != 2*iterations {
+		t.Error("Expected", 2*iterations, "names, saw", len(data.Names))
+	}
+	if len(data.Scores) != iterations {
+		t.Error("Expected", iterations, "scores, saw", len(data.Scores))
+	}
+}
+
+func TestConcurrentRWMutex(t *testing.T) {
+	data := &liveState{Scores: map[string]int{}}
+	lock := &sync.RWMutex{}
+	e := NewEditor(data, "/mutate", WithLocker(lock))
+	runConcurrently(t, e, lock, data)
+}
+
+func TestConcurrentMutex(t *testing.T) {
+	data := &liveState{Scores: map[string]int{}}
+	lock := &sync.Mutex{}
+	e := NewEditor(data, "/mutate", WithLocker(lock))
+	runConcurrently(t, e, lock, data)
+}
+
+func TestReadOnlyOption(t *testing.T) {
+	data := &modify{Foo: 5}
+	e := NewEditor(data, "/mutate", WithReadOnly())
+
+	if err := e.Mutate("Foo", OperatorSet("7")); err == nil {
+		t.Error("Expected read-only editor to refuse mutation")
+	}
+	if data.Foo != 5 {
+		t.Error("Expected Foo to be unchanged, was", data.Foo)
+	}
+	result, err := e.Render()
+	if err != nil {
+		t.Error("Rendering error:", err)
+	}
+	if strings.Contains(result, "change</button>") {
+		t.Error("Expected no mutation tools in read-only render, saw", result)
+	}
+}
+
+func TestPageOptions(t *testing.T) {
+	e := NewEditor(&modify{}, "/mutate",
+		WithTitle("Game <State>"),
+		WithMutatePath("/custom/mutate"))
+	result, err := e.Render()
+	if err != nil {
+		t.Error("Rendering error:", err)
+	}
+	if !strings.Contains(result, "<title>Game &lt;State&gt;</title>") {
+		t.Error("Expected escaped title in", result)
+	}
+	if !strings.Contains(result, `"/custom/mutate" + urlParams`) {
+		t.Error("Expected mutate path in", result)
+	}
+
+	e = NewEditor(&modify{Foo: 3}, "/mutate", WithPageTemplate(
+		template.Must(template.New("page").Parse("[{{.Content}}]"))))
+	result, err = e.Render()
+	if err != nil {
+		t.Error("Rendering error:", err)
+	}
+	if !strings.HasPrefix(result, "[&<div data-path=''>modify {") || !strings.HasSuffix(result, "]") {
+		t.Error("Expected custom page template, saw", result)
+	}
+}
+
+func TestMaxDepthOption(t *testing.T) {
+	data := struct {
+		Employees []testEmployee
+	}{
+		[]testEmployee{{Name: "Bob"}},
+	}
+	e := NewEditor(data, "", WithMaxDepth(2)).(*editor)
+	result, err := e.unwrappedRender()
+	if err != nil {
+		t.Error("Rendering error:", err)
+	}
+	expected := "<div data-path=''> {<ul><li>Employees: <div data-path='Employees'>[]structeditor.testEmployee {<ul><li>" +
+		"<div data-path='Employees.0'>structeditor.testEmployee {" +
+		"<button data-action='expand' data-path='Employees.0'>...</button>}</div>,</li>}</ul></div>,</li>}</ul></div>"
+	if result != expected {
+		t.Error("Expected", expected, "saw", result)
+	}
+}
+
+func TestMutateHooks(t *testing.T) {
+	data := &modify{Foo: 5}
+	var applied []string
+	e := NewEditor(data, "",
+		WithBeforeMutate(func(path string, operator Operator) error {
+			if path == "Bar" {
+				return errors.New("Bar is off limits")
+			}
+			return nil
+		}),
+		WithAfterMutate(func(path string, operator Operator) {
+			applied = append(applied, path)
+		}))
+
+	if err := e.Mutate("Foo", OperatorSet("7")); err != nil {
+		t.Error("Mutation error:", err)
+	}
+	if err := e.Mutate("Bar", OperatorSet("hi")); err == nil {
+		t.Error("Expected before hook to cancel mutation")
+	}
+	if data.Foo != 7 || data.Bar != "" {
+		t.Error("Expected only Foo to change, saw", data)
+	}
+	if !reflect.DeepEqual(applied, []string{"Foo"}) {
+		t.Error("Expected after hook to see [Foo], saw", applied)
+	}
+}
+
+func TestServeEditorOptions(t *testing.T) {
+	data := &modify{Foo: 5}
+	mux := http.NewServeMux()
+	ServeEditor(data, "/editor", mux,
+		WithMutatePath("/change"),
+		WithHandlerWrapper(func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "secret" {
+					http.Error(w, "forbidden", http.StatusForbidden)
+					return
+				}
+				h.ServeHTTP(w, r)
+			})
+		}))
+
+	unauthorized := httptest.NewRecorder()
+	mux.ServeHTTP(unauthorized, httptest.NewRequest("POST", "/change?operator=set&path=Foo&value=6", nil))
+	if unauthorized.Code != http.StatusForbidden || data.Foo != 5 {
+		t.Error("Expected unauthorized request to be refused, saw", unauthorized.Code, data.Foo)
+	}
+
+	req := httptest.NewRequest("POST", "/change?operator=set&path=Foo&value=7", nil)
+	req.Header.Set("Authorization", "secret")
+	authorized := httptest.NewRecorder()
+	mux.ServeHTTP(authorized, req)
+	if authorized.Code != http.StatusOK || data.Foo != 7 {
+		t.Error("Expected authorized request to succeed, saw", authorized.Code, data.Foo)
+	}
+}
+
+func TestPageSizeOption(t *testing.T) {
+	data := []int{0, 1, 2, 3, 4}
+	e := NewEditor(data, "", WithPageSize(2)).(*editor)
+	result, err := e.unwrappedRender()
+	if err != nil {
+		t.Error("Rendering error:", err)
+	}
+	expected := "<div data-path='' data-offset='0'>[]int {<ul><li>" + intString("0", "0") +
+		",</li><li>" + intString("1", "1") + ",</li>}<span class='page'>0-1 of 5</span>" +
+		"<button data-action='page' data-path='' data-offset='2'>&gt;</button></ul></div>"
+	if result != expected {
+		t.Error("Expected", expected, "saw", result)
+	}
+
+	pages := []struct {
+		offset int
+		result string
+	}{
+		{2, "<div data-path='' data-offset='2'>[]int {<ul><li>" + intString("2", "2") +
+			",</li><li>" + intString("3", "3") + ",</li>}" +
+			"<button data-action='page' data-path='' data-offset='0'>&lt;</button>" +
+			"<span class='page'>2-3 of 5</span>" +
+			"<button data-action='page' data-path='' data-offset='4'>&gt;</button></ul></div>"},
+		{10, "<div data-path='' data-offset='4'>[]int {<ul><li>" + intString("4", "4") +
+			",</li>}<button data-action='page' data-path='' data-offset='2'>&lt;</button>" +
+			"<span class='page'>4-4 of 5</span></ul></div>"},
+	}
+	for _, page := range pages {
+		var fragment strings.Builder
+		err = e.renderFragment(&fragment, "", page.offset)
+		if err != nil {
+			t.Error("Rendering error:", err)
+		}
+		if fragment.String() != page.result {
+			t.Error("Expected", page.result, "saw", fragment.String())
+		}
+	}
+}
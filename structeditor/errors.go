@@ -0,0 +1,82 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package structeditor
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Categories of errors returned by Mutate and OperatorFor. Use errors.Is to
+// check whether an error falls into one of these categories.
+var (
+	// The request could not be parsed: a malformed path, an unknown
+	// operator, or a value that cannot be converted to the target's type.
+	ErrInvalidInput = errors.New("invalid input")
+	// The path does not lead to a value.
+	ErrNotFound = errors.New("not found")
+	// The mutation conflicts with the current state, such as inserting a key
+	// that is already present.
+	ErrConflict = errors.New("conflict")
+	// The value at the path cannot be mutated.
+	ErrReadOnly = errors.New("read-only")
+)
+
+// An error in one of the categories above
+type editorError struct {
+	kind    error
+	message string
+}
+
+func (e *editorError) Error() string {
+	return e.message
+}
+
+func (e *editorError) Unwrap() error {
+	return e.kind
+}
+
+// newError creates an error of the specified kind with a formatted message.
+func newError(kind error, format string, args ...interface{}) error {
+	return &editorError{
+		kind:    kind,
+		message: fmt.Sprintf(format, args...),
+	}
+}
+
+// wrapError assigns an error (e.g. from a strconv parsing function) to the
+// specified kind, keeping its message.
+func wrapError(kind error, err error) error {
+	return &editorError{
+		kind:    kind,
+		message: err.Error(),
+	}
+}
+
+// statusCode returns the HTTP status code best describing an error.
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidInput):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrReadOnly):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
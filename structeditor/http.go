@@ -15,39 +15,123 @@
 package structeditor
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 // Handlers for serving the view interface via HTTP and handling mutation requests
 
+// How long ViewHandler may spend writing a page. The state is locked for
+// reading while the page is written, so this bounds how long a slow client
+// can hold up mutations.
+const viewWriteTimeout = 30 * time.Second
+
 // ViewHandler is an HTTP request handler that returns the structeditor user
-// interface.
+// interface. If the request has a "path" query parameter, only the HTML for
+// the value at that path is returned, starting at the element given by the
+// "offset" query parameter if the value is a long array or slice; the UI uses
+// this to load parts of the page on demand and to refresh the part of the
+// page affected by a mutation. If the "format" query parameter is "json", the
+// value at the path is returned as JSON instead, for the UI's JSON panels.
 func (e *editor) ViewHandler(w http.ResponseWriter, r *http.Request) {
-	result, err := e.Render()
-	if err != nil {
-		http.Error(w, err.Error(), 500)
+	var err error
+	query := r.URL.Query()
+	offset := 0
+	if offsetText := query.Get("offset"); offsetText != "" {
+		offset, err = strconv.Atoi(offsetText)
+		if err != nil {
+			err = newError(ErrInvalidInput, "Unable to parse offset '%s'", offsetText)
+			http.Error(w, err.Error(), statusCode(err))
+			return
+		}
+	}
+
+	if query.Get("format") == "json" {
+		data, err := e.valueJSON(query.Get("path"))
+		if err != nil {
+			http.Error(w, err.Error(), statusCode(err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	// Not every ResponseWriter supports deadlines; those that don't are
+	// written to without one.
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(viewWriteTimeout))
+	started := &startedWriter{w: w}
+	out := bufio.NewWriter(started)
+	if paths, ok := query["path"]; ok {
+		err = e.renderFragment(out, paths[0], offset)
 	} else {
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprintf(w, "%s", result)
+		err = e.RenderTo(out)
 	}
+	if err != nil {
+		if !started.started {
+			// Nothing has been sent, so discard the partial page and report
+			// the error in its place.
+			http.Error(w, err.Error(), statusCode(err))
+			return
+		}
+		fmt.Fprintf(out, "<p class='status error'>%s</p>", escape(err.Error()))
+	}
+	out.Flush()
+}
+
+// Records whether anything has been written to a response, after which its
+// status code can no longer be changed
+type startedWriter struct {
+	w       io.Writer
+	started bool
+}
+
+func (s *startedWriter) Write(p []byte) (int, error) {
+	s.started = true
+	return s.w.Write(p)
 }
 
 // MutateHandler is an HTTP request handler that modifies the editable state in
 // response to mutation operations (usually generated by the UI built by
-// ViewHandler).
+// ViewHandler). The response is a JSON object describing the mutation and,
+// if it failed, the error. The status code reflects the kind of failure: 400
+// for unparseable requests, 403 for read-only values, 404 for paths that do
+// not lead to a value and 409 for conflicts with the current state.
+// Mutations must be POSTed.
 func (e *editor) MutateHandler(w http.ResponseWriter, r *http.Request) {
-	values := r.URL.Query()
-	path := values.Get("path")
-	operator, err := e.OperatorFor(values)
-	if err != nil {
-		http.Error(w, err.Error(), 500)
+	if r.Method != http.MethodPost {
+		http.Error(w, "Mutations must be POSTed", http.StatusMethodNotAllowed)
 		return
 	}
-	err = e.Mutate(path, operator)
+	// Arguments may be sent in the body of the request as well as the URL,
+	// e.g. for documents too long for a URL
+	err := r.ParseForm()
+	values := r.Form
+	result := jsonMutationResult{
+		Path:     values.Get("path"),
+		Operator: values.Get("operator"),
+	}
+	status := http.StatusOK
+	var operator Operator
 	if err != nil {
-		http.Error(w, err.Error(), 500)
-		return
+		err = wrapError(ErrInvalidInput, err)
+	} else {
+		operator, err = e.OperatorFor(values)
+	}
+	if err == nil {
+		err = e.Mutate(result.Path, operator)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		status = statusCode(err)
 	}
-	http.Error(w, "", 200)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
 }
@@ -0,0 +1,142 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package structeditor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type httpExample struct {
+	Count  int
+	Scores map[string]int
+	Fixed  int `structeditor:"readonly"`
+	Boss   *testEmployee
+}
+
+func TestMutateHandlerStatus(t *testing.T) {
+	data := &httpExample{
+		Scores: map[string]int{"a": 1},
+		Boss:   &testEmployee{Name: "Bob"},
+	}
+	e := NewEditor(data, "/mutate")
+
+	steps := []struct {
+		query  string
+		status int
+	}{
+		{"operator=set&path=Count&value=5", http.StatusOK},
+		{"operator=set&path=Count&value=five", http.StatusBadRequest},
+		{"operator=explode&path=Count", http.StatusBadRequest},
+		{"operator=set&path=Missing&value=1", http.StatusNotFound},
+		{"operator=set&path=Scores.b&value=1", http.StatusNotFound},
+		{"operator=insert&path=Scores&key=a", http.StatusConflict},
+		{"operator=set&path=Fixed&value=1", http.StatusForbidden},
+		{"operator=resize&path=Scores&length=two", http.StatusBadRequest},
+		{"operator=zero&path=Fixed", http.StatusForbidden},
+	}
+
+	for _, step := range steps {
+		w := httptest.NewRecorder()
+		e.MutateHandler(w, httptest.NewRequest("POST", "/mutate?"+step.query, nil))
+		if w.Code != step.status {
+			t.Error(step.query, "- expected status", step.status, "saw", w.Code)
+		}
+		var result jsonMutationResult
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Error(step.query, "- unable to parse response:", err)
+		}
+		if (result.Error == "") != (step.status == http.StatusOK) {
+			t.Error(step.query, "- unexpected error in response:", result.Error)
+		}
+	}
+
+	if data.Count != 5 {
+		t.Error("Expected Count to be 5, saw", data.Count)
+	}
+
+	w := httptest.NewRecorder()
+	e.MutateHandler(w, httptest.NewRequest("GET", "/mutate?operator=set&path=Count&value=6", nil))
+	if w.Code != http.StatusMethodNotAllowed || data.Count != 5 {
+		t.Error("Expected GET to be refused, saw", w.Code, data.Count)
+	}
+}
+
+func TestViewHandlerFragment(t *testing.T) {
+	data := &httpExample{
+		Boss: &testEmployee{Name: "Bob", Id: "A"},
+	}
+	e := NewEditor(data, "/mutate")
+
+	w := httptest.NewRecorder()
+	e.ViewHandler(w, httptest.NewRequest("GET", "/?path=Boss", nil))
+	expected := "<div data-path='Boss'>testEmployee {<ul><li>Name: " + inputString("Bob", "Boss.Name") + editButtons("Boss.Name") +
+		",</li><li>Id: " + inputString("A", "Boss.Id") + editButtons("Boss.Id") +
+		",</li>}" + resetButton("Boss") + jsonPanel("Boss") + "</ul></div>"
+	if w.Code != http.StatusOK || w.Body.String() != expected {
+		t.Error("Expected", expected, "saw", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	e.ViewHandler(w, httptest.NewRequest("GET", "/?path=Nobody", nil))
+	if w.Code != http.StatusNotFound {
+		t.Error("Expected not found for missing path, saw", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	e.ViewHandler(w, httptest.NewRequest("GET", "/?path=Boss&offset=first", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Error("Expected bad request for unparseable offset, saw", w.Code)
+	}
+}
+
+type secretHolder struct {
+	Boss     testEmployee
+	Password string `structeditor:"secret"`
+}
+
+func TestValueJSON(t *testing.T) {
+	data := &secretHolder{Boss: testEmployee{Name: "Bob", Id: "A"}, Password: "hunter2"}
+	e := NewEditor(data, "/mutate")
+
+	w := httptest.NewRecorder()
+	e.ViewHandler(w, httptest.NewRequest("GET", "/?format=json&path=Boss", nil))
+	var boss testEmployee
+	if err := json.Unmarshal(w.Body.Bytes(), &boss); err != nil || boss != data.Boss {
+		t.Error("Expected", data.Boss, "saw", w.Code, w.Body.String())
+	}
+
+	for _, path := range []string{"", "Password"} {
+		w = httptest.NewRecorder()
+		e.ViewHandler(w, httptest.NewRequest("GET", "/?format=json&path="+path, nil))
+		if w.Code != http.StatusForbidden || strings.Contains(w.Body.String(), "hunter2") {
+			t.Error(path, "- expected secret to be refused, saw", w.Code, w.Body.String())
+		}
+	}
+
+	// Documents are sent in the body of the request
+	form := url.Values{"value": {`{"Name": "Alice", "Id": "B"}`}}
+	req := httptest.NewRequest("POST", "/mutate?operator=json&path=Boss", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	e.MutateHandler(w, req)
+	if w.Code != http.StatusOK || data.Boss.Name != "Alice" {
+		t.Error("Expected Boss to be set from JSON, saw", w.Code, w.Body.String(), data.Boss)
+	}
+}
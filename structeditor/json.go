@@ -0,0 +1,312 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package structeditor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+)
+
+// JSON API for reading the state and requesting mutations, for use by
+// scripts and tools rather than the HTML UI.
+
+// A node in the JSON representation of the state
+type jsonNode struct {
+	// Field name (or label), map key, or slice / array index of the node
+	// within its parent
+	Name string `json:"name,omitempty"`
+	// Path to the node, for use in mutation requests
+	Path string `json:"path"`
+	// Go type and kind of the node
+	Type string `json:"type"`
+	Kind string `json:"kind"`
+	// True if mutations of the node are allowed
+	Editable bool `json:"editable"`
+	// Scalar value of the node, formatted as accepted by the "set" operator
+	Value *string `json:"value,omitempty"`
+	// True if the node is a nil pointer, interface, slice or map
+	Nil bool `json:"nil,omitempty"`
+	// True if the node is a struct field that is not exported
+	Unexported bool `json:"unexported,omitempty"`
+	// True if the node's value is hidden because it is tagged as secret
+	Secret bool `json:"secret,omitempty"`
+	// Description of the node's value from its String method, if it has one
+	// (and is not shown in its text form)
+	Description string `json:"description,omitempty"`
+	// If the node is a pointer, map or slice referring to the same value as
+	// one seen earlier in the tree, the path of the earlier node. The node's
+	// children are not repeated.
+	AliasOf *string `json:"aliasOf,omitempty"`
+	// Fields, elements or entries of a composite node, or the value
+	// referenced by a pointer or held by an interface
+	Children []*jsonNode `json:"children,omitempty"`
+}
+
+// A single mutation in a request to JSONMutateHandler. Args are passed to
+// OperatorFor alongside the operator name (e.g. "value" for "set").
+type jsonMutation struct {
+	Path     string            `json:"path"`
+	Operator string            `json:"operator"`
+	Args     map[string]string `json:"args,omitempty"`
+}
+
+// The outcome of a single mutation requested of JSONMutateHandler
+type jsonMutationResult struct {
+	Path     string `json:"path"`
+	Operator string `json:"operator"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Builds the JSON representation of the state
+type nodeBuilder struct {
+	// If true, unexported struct fields are editable (see
+	// WithUnexportedMutation)
+	editUnexported bool
+	// Pointers, maps and slices already added to the tree
+	visited visitedReferences
+}
+
+func (e *editor) buildTree() (*jsonNode, error) {
+	defer e.lockForRead()()
+	b := nodeBuilder{
+		editUnexported: e.editUnexported,
+		visited:        visitedReferences{},
+	}
+	v := reflect.ValueOf(e.state)
+	editable := v.Kind() == reflect.Ptr && !e.readOnly
+	return b.build(v, "", nil, editable, fieldTag{})
+}
+
+func (b *nodeBuilder) build(v reflect.Value, name string, curPath *Path, editable bool, tag fieldTag) (*jsonNode, error) {
+	if !v.IsValid() {
+		return nil, fmt.Errorf("At [%v]: Unable to build node for invalid value", curPath)
+	}
+	node := &jsonNode{
+		Name:     name,
+		Path:     curPath.String(),
+		Type:     v.Type().String(),
+		Kind:     v.Kind().String(),
+		Editable: editable,
+		Secret:   tag.secret,
+	}
+	if description, ok := describe(v); ok && !tag.secret {
+		node.Description = description
+	}
+	if text, ok := formatScalar(v); ok {
+		if !tag.secret {
+			node.Value = &text
+		}
+		node.Editable = editable && !marshalsTextOnly(v.Type())
+		return node, nil
+	}
+	if first, seen := b.visited.visit(v, curPath); seen {
+		node.AliasOf = &first
+		return node, nil
+	}
+
+	var err error
+	addChild := func(subvalue reflect.Value, name string, element *Path, editable bool, tag fieldTag) *jsonNode {
+		var child *jsonNode
+		curPath.Visiting(element, func(updatedPath *Path) {
+			child, err = b.build(subvalue, name, updatedPath, editable, tag)
+		})
+		if child != nil {
+			node.Children = append(node.Children, child)
+		}
+		return child
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField() && err == nil; i++ {
+			sf := t.Field(i)
+			sfTag := parseFieldTag(sf)
+			if sfTag.hidden {
+				continue
+			}
+			label := sf.Name
+			if sfTag.label != "" {
+				label = sfTag.label
+			}
+			exported := sf.PkgPath == ""
+			childTag := tag.enclosing(sfTag)
+			childEditable := editable && (exported || b.editUnexported) && !childTag.readOnly
+			child := addChild(v.Field(i), label, &Path{Name: sf.Name}, childEditable, childTag)
+			if child != nil {
+				child.Unexported = !exported
+			}
+		}
+	case reflect.Array, reflect.Slice:
+		node.Nil = v.Kind() == reflect.Slice && v.IsNil()
+		for i := 0; i < v.Len() && err == nil; i++ {
+			addChild(v.Index(i), strconv.Itoa(i), &Path{Index: i}, editable, tag)
+		}
+	case reflect.Map:
+		node.Nil = v.IsNil()
+		if tag.secret {
+			// The keys of a secret map may be as sensitive as its values
+			break
+		}
+		for _, entry := range sortedMapEntries(v) {
+			if err != nil {
+				break
+			}
+			keyText := formatMapKey(entry.key)
+			addChild(entry.value, keyText, namePath(keyText), editable, tag)
+		}
+	case reflect.Ptr, reflect.Interface:
+		node.Nil = v.IsNil()
+		if !v.IsNil() {
+			var child *jsonNode
+			child, err = b.build(v.Elem(), "", curPath, editable, tag)
+			if child != nil {
+				node.Children = append(node.Children, child)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("At [%v]: Unknown composite render type: %v", curPath, v.Kind())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// valueJSON returns the value at path encoded with encoding/json, as edited
+// in the UI's JSON panels. Values with secret or hidden fields are refused,
+// since encoding/json would reveal them.
+func (e *editor) valueJSON(path string) ([]byte, error) {
+	p, err := StringToPath(path)
+	if err != nil {
+		return nil, wrapError(ErrInvalidInput, err)
+	}
+	defer e.lockForRead()()
+	found, err := e.findValueToChange(p, reflect.ValueOf(e.state), true, fieldTag{})
+	if err != nil {
+		return nil, err
+	}
+	if found.tag.secret || hasProtectedFields(found.value, visitedReferences{}) {
+		return nil, newError(ErrReadOnly, "Value at '%s' has secret or hidden fields", path)
+	}
+	if !found.value.CanInterface() {
+		return nil, newError(ErrReadOnly, "Unable to encode value at '%s' obtained from unexported field", path)
+	}
+	return json.MarshalIndent(found.value.Interface(), "", "  ")
+}
+
+// hasProtectedFields returns true if v contains a struct field tagged as
+// secret or hidden.
+func hasProtectedFields(v reflect.Value, visited visitedReferences) bool {
+	if _, seen := visited.visit(v, nil); seen {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return !v.IsNil() && hasProtectedFields(v.Elem(), visited)
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if hasProtectedFields(v.Index(i), visited) {
+				return true
+			}
+		}
+	case reflect.Map:
+		entries := v.MapRange()
+		for entries.Next() {
+			if hasProtectedFields(entries.Key(), visited) || hasProtectedFields(entries.Value(), visited) {
+				return true
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			tag := parseFieldTag(t.Field(i))
+			if tag.secret || tag.hidden || hasProtectedFields(v.Field(i), visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// JSONViewHandler is an HTTP request handler that returns the state as a JSON
+// tree of nodes, each describing its path, type, kind, editability and (for
+// scalars) value.
+func (e *editor) JSONViewHandler(w http.ResponseWriter, r *http.Request) {
+	tree, err := e.buildTree()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tree)
+}
+
+// JSONMutateHandler is an HTTP request handler that applies a batch of
+// mutations, sent as a JSON array of {"path", "operator", "args"} objects in
+// the body of a POST request. Mutations are applied in order; if one fails,
+// the rest are not attempted. The response lists the outcome of each mutation
+// attempted; its status code is that of the failed mutation, as for
+// MutateHandler.
+func (e *editor) JSONMutateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Mutations must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	var mutations []jsonMutation
+	err := json.NewDecoder(r.Body).Decode(&mutations)
+	if err != nil {
+		http.Error(w, "Unable to parse mutations: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status := http.StatusOK
+	results := []jsonMutationResult{}
+	for _, mutation := range mutations {
+		result := jsonMutationResult{
+			Path:     mutation.Path,
+			Operator: mutation.Operator,
+		}
+		err := e.mutateFromJSON(mutation)
+		if err != nil {
+			result.Error = err.Error()
+			status = statusCode(err)
+		}
+		results = append(results, result)
+		if err != nil {
+			break
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(results)
+}
+
+func (e *editor) mutateFromJSON(mutation jsonMutation) error {
+	values := url.Values{}
+	for name, value := range mutation.Args {
+		values.Set(name, value)
+	}
+	values.Set("operator", mutation.Operator)
+	operator, err := e.OperatorFor(values)
+	if err != nil {
+		return err
+	}
+	return e.Mutate(mutation.Path, operator)
+}
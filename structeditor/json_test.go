@@ -0,0 +1,155 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package structeditor
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type jsonExample struct {
+	Name     string
+	Scores   map[string]int
+	Boss     *testEmployee
+	Password string `structeditor:"secret"`
+	Fixed    []int  `structeditor:"readonly"`
+	private  bool
+}
+
+func TestJSONView(t *testing.T) {
+	data := &jsonExample{
+		Name:     "ExampleCo",
+		Scores:   map[string]int{"b": 2, "a": 1},
+		Password: "hunter2",
+		Fixed:    []int{4},
+	}
+	e := NewEditor(data, "")
+
+	w := httptest.NewRecorder()
+	e.JSONViewHandler(w, httptest.NewRequest("GET", "/json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatal("Expected OK, saw", w.Code, w.Body.String())
+	}
+
+	var tree jsonNode
+	if err := json.Unmarshal(w.Body.Bytes(), &tree); err != nil {
+		t.Fatal("Unable to parse response:", err)
+	}
+
+	str := func(s string) *string { return &s }
+	expected := jsonNode{
+		Path: "", Type: "*structeditor.jsonExample", Kind: "ptr", Editable: true,
+		Children: []*jsonNode{{
+			Path: "", Type: "structeditor.jsonExample", Kind: "struct", Editable: true,
+			Children: []*jsonNode{
+				{Name: "Name", Path: "Name", Type: "string", Kind: "string", Editable: true, Value: str("ExampleCo")},
+				{Name: "Scores", Path: "Scores", Type: "map[string]int", Kind: "map", Editable: true,
+					Children: []*jsonNode{
+						{Name: "a", Path: "Scores.a", Type: "int", Kind: "int", Editable: true, Value: str("1")},
+						{Name: "b", Path: "Scores.b", Type: "int", Kind: "int", Editable: true, Value: str("2")},
+					}},
+				{Name: "Boss", Path: "Boss", Type: "*structeditor.testEmployee", Kind: "ptr", Editable: true, Nil: true},
+				{Name: "Password", Path: "Password", Type: "string", Kind: "string", Editable: true, Secret: true},
+				{Name: "Fixed", Path: "Fixed", Type: "[]int", Kind: "slice",
+					Children: []*jsonNode{
+						{Name: "0", Path: "Fixed.0", Type: "int", Kind: "int", Value: str("4")},
+					}},
+				{Name: "private", Path: "private", Type: "bool", Kind: "bool", Value: str("false"), Unexported: true},
+			},
+		}},
+	}
+	if !reflect.DeepEqual(tree, expected) {
+		saw, _ := json.Marshal(tree)
+		wanted, _ := json.Marshal(expected)
+		t.Error("Expected", string(wanted), "saw", string(saw))
+	}
+}
+
+func TestJSONMutate(t *testing.T) {
+	data := &jsonExample{
+		Scores: map[string]int{},
+	}
+	e := NewEditor(data, "")
+
+	body := `[
+		{"path": "Name", "operator": "set", "args": {"value": "NewCo"}},
+		{"path": "Scores", "operator": "insert", "args": {"key": "x", "value": "3"}},
+		{"path": "Fixed", "operator": "grow"},
+		{"path": "Name", "operator": "set", "args": {"value": "Unreached"}}
+	]`
+	w := httptest.NewRecorder()
+	e.JSONMutateHandler(w, httptest.NewRequest("POST", "/json/mutate", strings.NewReader(body)))
+
+	var results []jsonMutationResult
+	if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+		t.Fatal("Unable to parse response:", err, w.Body.String())
+	}
+	if len(results) != 3 || results[0].Error != "" || results[1].Error != "" || results[2].Error == "" {
+		t.Error("Expected two successes and a failure, saw", results)
+	}
+	if w.Code == http.StatusOK {
+		t.Error("Expected failure status, saw", w.Code)
+	}
+	if data.Name != "NewCo" || data.Scores["x"] != 3 || len(data.Fixed) != 0 {
+		t.Error("Expected first two mutations to apply, saw", data)
+	}
+
+	w = httptest.NewRecorder()
+	e.JSONMutateHandler(w, httptest.NewRequest("POST", "/json/mutate", strings.NewReader("{not json")))
+	if w.Code != http.StatusBadRequest {
+		t.Error("Expected bad request for malformed body, saw", w.Code)
+	}
+}
+
+func TestJSONViewCycles(t *testing.T) {
+	first := &listNode{Value: 1}
+	first.Next = first
+	e := NewEditor(first, "")
+
+	w := httptest.NewRecorder()
+	e.JSONViewHandler(w, httptest.NewRequest("GET", "/json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatal("Expected OK, saw", w.Code, w.Body.String())
+	}
+
+	var tree jsonNode
+	if err := json.Unmarshal(w.Body.Bytes(), &tree); err != nil {
+		t.Fatal("Unable to parse response:", err)
+	}
+	next := tree.Children[0].Children[1]
+	if next.AliasOf == nil || *next.AliasOf != "" || len(next.Children) != 0 {
+		saw, _ := json.Marshal(next)
+		t.Error("Expected Next to be an alias of the top level, saw", string(saw))
+	}
+}
+
+func TestJSONViewNaNKeys(t *testing.T) {
+	e := NewEditor(map[float64]int{math.NaN(): 1}, "")
+	w := httptest.NewRecorder()
+	e.JSONViewHandler(w, httptest.NewRequest("GET", "/json", nil))
+	var tree jsonNode
+	if err := json.Unmarshal(w.Body.Bytes(), &tree); err != nil {
+		t.Fatal("Unable to parse response:", err, w.Body.String())
+	}
+	if len(tree.Children) != 1 || tree.Children[0].Name != "NaN" || tree.Children[0].Value == nil || *tree.Children[0].Value != "1" {
+		saw, _ := json.Marshal(tree)
+		t.Error("Expected the NaN entry, saw", string(saw))
+	}
+}
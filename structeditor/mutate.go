@@ -15,11 +15,12 @@
 package structeditor
 
 import (
-	"errors"
-	"fmt"
+	"bytes"
+	"net/http"
 	"net/url"
 	"reflect"
 	"strconv"
+	"unsafe"
 )
 
 type Operator interface {
@@ -29,57 +30,230 @@ type Operator interface {
 }
 
 func (e *editor) Mutate(path string, operator Operator) error {
+	if e.readOnly {
+		return newError(ErrReadOnly, "Editor is read-only")
+	}
+	if reflect.ValueOf(e.state).Kind() != reflect.Ptr {
+		// Only state reached through a pointer can be changed in place
+		return newError(ErrReadOnly, "Editor state is not a pointer, so is read-only")
+	}
+	if e.beforeMutate != nil {
+		err := e.beforeMutate(path, operator)
+		if err != nil {
+			return err
+		}
+	}
+	err := e.mutate(path, operator)
+	if err != nil {
+		return err
+	}
+	if e.afterMutate != nil {
+		e.afterMutate(path, operator)
+	}
+	return nil
+}
+
+// mutate runs the operator on the value at path while holding the state lock.
+func (e *editor) mutate(path string, operator Operator) error {
 	p, err := StringToPath(path)
+	if err != nil {
+		return wrapError(ErrInvalidInput, err)
+	}
+
+	defer e.lockForWrite()()
+
+	found, err := e.findValueToChange(p, reflect.ValueOf(e.state), operator.ModifiesPointer(), fieldTag{})
 	if err != nil {
 		return err
 	}
+	if found.tag.readOnly {
+		return newError(ErrReadOnly, "Value at '%s' is read-only", path)
+	}
+	found.tag.enum = allowedValues(found.value, found.tag)
+	if constrained, ok := operator.(constrainedOperator); ok {
+		err = constrained.checkConstraints(found.value, found.tag)
+		if err != nil {
+			return err
+		}
+	}
 
-	v, err := e.findValueToChange(p, reflect.ValueOf(e.state), operator.ModifiesPointer())
+	if tagged, ok := operator.(taggedOperator); ok {
+		err = tagged.doTagged(found.value, found.tag)
+	} else {
+		err = operator.Do(found.value)
+	}
 	if err != nil {
 		return err
 	}
+	return found.commit()
+}
+
+// commitFunc writes a changed value back into its container. Map entries
+// and values held by interfaces are not addressable, so findValueToChange
+// hands out an addressable copy of them; the returned commitFunc stores the
+// copy back into the container once the operator has run, or returns an
+// error if the container cannot be written to.
+type commitFunc func() error
 
-	return operator.Do(v)
+func noCommit() error {
+	return nil
 }
 
-func (e *editor) findValueToChange(p *Path, v reflect.Value, modifiesPtr bool) (reflect.Value, error) {
-	if p == nil {
-		return v, nil
+// A value located by findValueToChange
+type target struct {
+	value  reflect.Value
+	commit commitFunc
+	// Struct tag settings that apply to the value, including those inherited
+	// from enclosing fields
+	tag fieldTag
+}
+
+// constrainedOperator is implemented by operators that must respect the
+// constraints set by struct tags on the value they change.
+type constrainedOperator interface {
+	checkConstraints(v reflect.Value, tag fieldTag) error
+}
+
+// taggedOperator is implemented by operators that replace a value along with
+// everything inside it, and so check the constraints set by struct tags on
+// the values inside it themselves. doTagged is called in place of Do, with
+// the struct tag settings that apply to the value.
+type taggedOperator interface {
+	doTagged(v reflect.Value, tag fieldTag) error
+}
+
+// checkAllowed returns an error if the struct tag settings (or v's type)
+// restrict v to a list of values and v is not one of them.
+func checkAllowed(v reflect.Value, tag fieldTag) error {
+	tag.enum = allowedValues(v, tag)
+	if text, ok := formatScalar(v); ok && !tag.allows(text) {
+		return newError(ErrInvalidInput, "'%s' is not one of the allowed values %v", text, tag.enum)
+	}
+	return nil
+}
+
+// findValueToChange follows the path from v to the value it addresses.
+// Pointers and interfaces along the way are looked through; if the path ends
+// at one, it is the target itself only if modifiesPtr is set, and otherwise
+// the value it refers to is.
+func (e *editor) findValueToChange(p *Path, v reflect.Value, modifiesPtr bool, tag fieldTag) (target, error) {
+	if p == nil && (modifiesPtr || (v.Kind() != reflect.Interface && v.Kind() != reflect.Ptr)) {
+		return target{v, noCommit, tag}, nil
 	}
 	switch v.Kind() {
-	case reflect.Ptr, reflect.Interface:
-		if modifiesPtr {
-			return v, nil
-		} else {
-			dereferenced := v.Elem()
-			if !dereferenced.IsValid() {
-				return reflect.Value{}, errors.New("Attempted to dereference nil pointer.")
+	case reflect.Interface:
+		contents := v.Elem()
+		if !contents.IsValid() {
+			return target{}, newError(ErrNotFound, "Attempted to look inside nil interface.")
+		}
+		if contents.Kind() == reflect.Ptr {
+			return e.findValueToChange(p, contents, modifiesPtr, tag)
+		}
+		// Values held by an interface are not addressable, so edit a copy and
+		// store the copy back into the interface.
+		if !contents.CanInterface() {
+			return target{}, newError(ErrReadOnly, "Unable to look inside interface obtained from unexported field")
+		}
+		contentsCopy := reflect.New(contents.Type()).Elem()
+		contentsCopy.Set(contents)
+		found, err := e.findValueToChange(p, contentsCopy, modifiesPtr, tag)
+		if err != nil {
+			return target{}, err
+		}
+		commitInner := found.commit
+		found.commit = func() error {
+			err := commitInner()
+			if err != nil {
+				return err
+			}
+			if !v.CanSet() {
+				return newError(ErrReadOnly, "Unable to store value into unaddressable %v", v.Type())
 			}
-			return e.findValueToChange(p, dereferenced, modifiesPtr)
+			v.Set(contentsCopy)
+			return nil
+		}
+		return found, nil
+	case reflect.Ptr:
+		dereferenced := v.Elem()
+		if !dereferenced.IsValid() {
+			return target{}, newError(ErrNotFound, "Attempted to dereference nil pointer.")
 		}
+		return e.findValueToChange(p, dereferenced, modifiesPtr, tag)
 	case reflect.Struct:
 		if p.Name == "" {
-			return reflect.Value{}, errors.New("Attempted numeric indexing on a struct or interface.")
+			return target{}, newError(ErrNotFound, "Attempted numeric indexing on a struct or interface.")
 		}
-		el := v.FieldByName(p.Name)
-		if !el.IsValid() {
-			return reflect.Value{}, errors.New("No field by name '" + p.Name + "'")
+		sf, ok := v.Type().FieldByName(p.Name)
+		sfTag := parseFieldTag(sf)
+		if !ok || sfTag.hidden {
+			return target{}, newError(ErrNotFound, "No field by name '%s'", p.Name)
+		}
+		el := v.FieldByIndex(sf.Index)
+		if sf.PkgPath != "" {
+			// Unexported fields are read-only unless WithUnexportedMutation
+			// is used.
+			sfTag.readOnly = sfTag.readOnly || !e.editUnexported
+			if el.CanAddr() {
+				// Bypass reflect's read-only restriction on unexported fields
+				el = reflect.NewAt(el.Type(), unsafe.Pointer(el.UnsafeAddr())).Elem()
+			} else if e.editUnexported {
+				return target{}, newError(ErrReadOnly, "Field '%s' is unexported and not addressable", p.Name)
+			}
 		}
-		return e.findValueToChange(p.Next, el, modifiesPtr)
+		return e.findValueToChange(p.Next, el, modifiesPtr, tag.enclosing(sfTag))
 	case reflect.Array, reflect.Slice:
 		if p.Name != "" {
-			return reflect.Value{}, errors.New("Attempted to index into array or slice using a name string '" + p.Name + "'.")
+			return target{}, newError(ErrNotFound, "Attempted to index into array or slice using a name string '%s'.", p.Name)
 		}
-		if v.Len() <= p.Index {
-			return reflect.Value{}, fmt.Errorf("Attempted to fetch element %d, but array or slice is length %d", p.Index, v.Len())
+		if p.Index < 0 || v.Len() <= p.Index {
+			return target{}, newError(ErrNotFound, "Attempted to fetch element %d, but array or slice is length %d", p.Index, v.Len())
 		}
 		el := v.Index(p.Index)
-		return e.findValueToChange(p.Next, el, modifiesPtr)
+		return e.findValueToChange(p.Next, el, modifiesPtr, tag)
+	case reflect.Map:
+		key, err := parseMapKey(p.part(), v.Type().Key())
+		if err != nil {
+			return target{}, err
+		}
+		el := v.MapIndex(key)
+		if !el.IsValid() {
+			return target{}, newError(ErrNotFound, "No map entry with key '%s'", p.part())
+		}
+		if !el.CanInterface() {
+			return target{}, newError(ErrReadOnly, "Unable to look inside map obtained from unexported field")
+		}
+		elCopy := reflect.New(el.Type()).Elem()
+		elCopy.Set(el)
+		found, err := e.findValueToChange(p.Next, elCopy, modifiesPtr, tag)
+		if err != nil {
+			return target{}, err
+		}
+		commitInner := found.commit
+		found.commit = func() error {
+			err := commitInner()
+			if err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elCopy)
+			return nil
+		}
+		return found, nil
 	}
-	return reflect.Value{}, errors.New("Could not follow path through element with type '" + v.Kind().String() + "'")
+	return target{}, newError(ErrNotFound, "Could not follow path through element with type '%v'", v.Kind())
 
 }
 
+// parseMapKey converts the string form of a map key (as found in a Path) into
+// a value of the map's key type.
+func parseMapKey(s string, keyType reflect.Type) (reflect.Value, error) {
+	key := reflect.New(keyType).Elem()
+	err := OperatorSet(s).Do(key)
+	if err != nil {
+		return reflect.Value{}, newError(ErrInvalidInput, "Unable to use '%s' as map key of type %v: %v", s, keyType, err)
+	}
+	return key, nil
+}
+
 /// Operators
 
 // Set a value to a new value (indicated by a string)
@@ -95,13 +269,35 @@ func (o *operatorSet) ModifiesPointer() bool {
 	return false
 }
 
+func (o *operatorSet) checkConstraints(v reflect.Value, tag fieldTag) error {
+	if !tag.allows(o.newValue) {
+		return newError(ErrInvalidInput, "'%s' is not one of the allowed values %v", o.newValue, tag.enum)
+	}
+	return nil
+}
+
 func (o *operatorSet) Do(v reflect.Value) error {
+	if set, err := setRegistered(v, o.newValue); set {
+		return err
+	}
+	if _, ok := wellKnownTypes[v.Type()]; ok {
+		return setWellKnown(v, o.newValue)
+	}
+	if implements(v.Type(), textUnmarshalerType) && v.CanAddr() {
+		return setText(v, o.newValue)
+	}
+	if marshalsTextOnly(v.Type()) {
+		return newError(ErrReadOnly, "Unable to parse values of type %v", v.Type())
+	}
 	switch v.Kind() {
 	case reflect.Int, reflect.Int8, reflect.Int16,
 		reflect.Int32, reflect.Int64:
 		newValue, err := strconv.ParseInt(o.newValue, 0, 64)
 		if err != nil {
-			return err
+			return wrapError(ErrInvalidInput, err)
+		}
+		if v.OverflowInt(newValue) {
+			return newError(ErrInvalidInput, "Value %d is out of range for type %v", newValue, v.Type())
 		}
 		v.SetInt(newValue)
 		return nil
@@ -109,33 +305,296 @@ func (o *operatorSet) Do(v reflect.Value) error {
 		reflect.Uint32, reflect.Uint64:
 		newValue, err := strconv.ParseUint(o.newValue, 0, 64)
 		if err != nil {
-			return err
+			return wrapError(ErrInvalidInput, err)
 
 		}
+		if v.OverflowUint(newValue) {
+			return newError(ErrInvalidInput, "Value %d is out of range for type %v", newValue, v.Type())
+		}
 		v.SetUint(newValue)
 		return nil
 	case reflect.Float32, reflect.Float64:
 		newValue, err := strconv.ParseFloat(o.newValue, 64)
 		if err != nil {
-			return err
+			return wrapError(ErrInvalidInput, err)
+		}
+		if v.OverflowFloat(newValue) {
+			return newError(ErrInvalidInput, "Value %v is out of range for type %v", newValue, v.Type())
 		}
 		v.SetFloat(newValue)
+	case reflect.Complex64, reflect.Complex128:
+		newValue, err := strconv.ParseComplex(o.newValue, v.Type().Bits())
+		if err != nil {
+			return wrapError(ErrInvalidInput, err)
+		}
+		if v.OverflowComplex(newValue) {
+			return newError(ErrInvalidInput, "Value %v is out of range for type %v", newValue, v.Type())
+		}
+		v.SetComplex(newValue)
 	case reflect.String:
 		v.SetString(o.newValue)
 		return nil
 	case reflect.Bool:
 		newValue, err := strconv.ParseBool(o.newValue)
 		if err != nil {
-			return err
+			return wrapError(ErrInvalidInput, err)
 		}
 		v.SetBool(newValue)
 		return nil
 	default:
-		return fmt.Errorf("Unable to set value on type %v", v.Kind())
+		return newError(ErrInvalidInput, "Unable to set value on type %v", v.Kind())
+	}
+	return nil
+}
+
+// Replace a struct, array, slice, map or pointer with a value decoded from a
+// JSON document, which must match the value's type. Fields that cannot be
+// set from JSON (unexported fields and those hidden by struct tags) keep
+// their current values, and fields tagged read-only must not be changed.
+// Nil pointers are pointed at the decoded value; other pointers and
+// interfaces have the value they refer to replaced.
+type operatorSetJSON struct {
+	document string
+}
+
+func OperatorSetJSON(document string) Operator {
+	return &operatorSetJSON{document}
+}
+
+func (o *operatorSetJSON) ModifiesPointer() bool {
+	return true
+}
+
+func (o *operatorSetJSON) Do(v reflect.Value) error {
+	return o.doTagged(v, fieldTag{})
+}
+
+func (o *operatorSetJSON) doTagged(v reflect.Value, tag fieldTag) error {
+	switch {
+	case v.Kind() == reflect.Ptr && !v.IsNil():
+		v = v.Elem()
+	case v.Kind() == reflect.Interface && !v.IsNil():
+		contents := v.Elem()
+		if contents.Kind() == reflect.Ptr {
+			return o.doTagged(contents, tag)
+		}
+		// Values held by an interface are not addressable, so decode into a
+		// copy and store the copy back into the interface.
+		if !v.CanSet() || !contents.CanInterface() {
+			return newError(ErrReadOnly, "Unable to set value held by unaddressable %v", v.Type())
+		}
+		contentsCopy := reflect.New(contents.Type()).Elem()
+		contentsCopy.Set(contents)
+		err := o.doTagged(contentsCopy, tag)
+		if err != nil {
+			return err
+		}
+		v.Set(contentsCopy)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Array, reflect.Slice, reflect.Map, reflect.Ptr:
+	default:
+		return newError(ErrInvalidInput, "Unable to set type %v from JSON", v.Kind())
 	}
+	return decodeInto(v, tag, jsonFormat, []byte(o.document), false)
+}
+
+// Replace a value with one decoded from a snapshot in the named format, as
+// exported by ExportHandler. Secret, hidden and unexported fields keep their
+// current values, and fields tagged read-only must not be changed.
+type operatorImport struct {
+	format   string
+	snapshot []byte
+}
+
+func OperatorImport(format string, snapshot []byte) Operator {
+	return &operatorImport{format, snapshot}
+}
+
+func (o *operatorImport) ModifiesPointer() bool {
+	return false
+}
+
+func (o *operatorImport) Do(v reflect.Value) error {
+	return o.doTagged(v, fieldTag{})
+}
+
+func (o *operatorImport) doTagged(v reflect.Value, tag fieldTag) error {
+	format, err := snapshotFormatNamed(o.format)
+	if err != nil {
+		return err
+	}
+	return decodeInto(v, tag, format, o.snapshot, true)
+}
+
+// decodeInto replaces v, to which the specified struct tag settings apply,
+// with a value of the same type decoded from data in the specified format.
+// Fields that the format cannot set, hidden fields and (if keepSecrets is
+// set) secret fields keep their current values.
+func decodeInto(v reflect.Value, tag fieldTag, format snapshotFormat, data []byte, keepSecrets bool) error {
+	if !v.CanSet() {
+		return newError(ErrReadOnly, "Unable to set unaddressable %v", v.Type())
+	}
+	decoded := reflect.New(v.Type())
+	err := format.decode(bytes.NewReader(data), decoded.Interface())
+	if err != nil {
+		return newError(ErrInvalidInput, "Unable to decode %v from %s: %v", v.Type(), format.name, err)
+	}
+	err = fieldKeeper{format, keepSecrets}.keep(decoded.Elem(), v, tag)
+	if err != nil {
+		return err
+	}
+	v.Set(decoded.Elem())
 	return nil
 }
 
+// Keeps the values of the struct fields that a decoded value must not change
+// in the value it replaces
+type fieldKeeper struct {
+	// The format the value was decoded from; fields it cannot set are kept
+	format snapshotFormat
+	// If true, secret fields are kept too
+	keepSecrets bool
+}
+
+// keep copies the fields of the structs in current that must be kept into the
+// corresponding structs in decoded, which was decoded to replace current, and
+// checks that the fields with read-only or enum struct tags were not changed
+// or set to values not allowed. Elements of arrays and slices correspond to
+// those at the same index in current, and map entries to those with the same
+// key. Values with nothing corresponding to them (such as new elements, or
+// the target of a pointer that was nil) are passed an invalid current, and
+// have the fields that must be kept zeroed instead. Values that the format
+// decodes through their own methods (such as time.Time) are not looked
+// inside. tag holds the struct tag settings that apply to decoded.
+func (k fieldKeeper) keep(decoded reflect.Value, current reflect.Value, tag fieldTag) error {
+	err := checkAllowed(decoded, tag)
+	if err != nil {
+		return err
+	}
+	if k.format.decodesItself(decoded.Type()) {
+		return nil
+	}
+	if current.IsValid() {
+		current, err = readable(current)
+		if err != nil {
+			return err
+		}
+	}
+	switch decoded.Kind() {
+	case reflect.Ptr:
+		if decoded.IsNil() {
+			return nil
+		}
+		return k.keep(decoded.Elem(), elemOf(current), tag)
+	case reflect.Interface:
+		if decoded.IsNil() {
+			return nil
+		}
+		// Values held by interfaces are not addressable, so work on a copy
+		contents := reflect.New(decoded.Elem().Type()).Elem()
+		contents.Set(decoded.Elem())
+		currentContents := elemOf(current)
+		if currentContents.IsValid() && currentContents.Type() != contents.Type() {
+			currentContents = reflect.Value{}
+		}
+		err := k.keep(contents, currentContents, tag)
+		if err != nil {
+			return err
+		}
+		decoded.Set(contents)
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < decoded.Len(); i++ {
+			var currentElement reflect.Value
+			if current.IsValid() && i < current.Len() {
+				currentElement = current.Index(i)
+			}
+			err := k.keep(decoded.Index(i), currentElement, tag)
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if decoded.IsNil() {
+			return nil
+		}
+		// Map entries are not addressable, so build a map of copies. Keys
+		// not equal to themselves (NaN) cannot be looked up, so each entry
+		// is read together with its key, and has no counterpart in current.
+		kept := reflect.MakeMapWithSize(decoded.Type(), decoded.Len())
+		for iter := decoded.MapRange(); iter.Next(); {
+			element := reflect.New(decoded.Type().Elem()).Elem()
+			element.Set(iter.Value())
+			var currentElement reflect.Value
+			if current.IsValid() {
+				currentElement = current.MapIndex(iter.Key())
+			}
+			err := k.keep(element, currentElement, tag)
+			if err != nil {
+				return err
+			}
+			kept.SetMapIndex(iter.Key(), element)
+		}
+		decoded.Set(kept)
+	case reflect.Struct:
+		t := decoded.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := tag.enclosing(parseFieldTag(sf))
+			field := decoded.Field(i)
+			field = reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+			var currentField reflect.Value
+			if current.IsValid() {
+				currentField = current.Field(i)
+			}
+			switch {
+			case tag.hidden || (k.keepSecrets && tag.secret) || !k.format.sets(sf):
+				if !currentField.IsValid() {
+					field.Set(reflect.Zero(field.Type()))
+					continue
+				}
+				currentField, err := readable(currentField)
+				if err != nil {
+					return err
+				}
+				field.Set(currentField)
+			case tag.readOnly:
+				// Fields inside that are kept must not count as changes
+				err := k.keep(field, currentField, tag)
+				if err != nil {
+					return err
+				}
+				if !currentField.IsValid() {
+					continue
+				}
+				currentField, err = readable(currentField)
+				if err != nil {
+					return err
+				}
+				if !reflect.DeepEqual(field.Interface(), currentField.Interface()) {
+					return newError(ErrReadOnly, "Field '%s' is read-only", sf.Name)
+				}
+			default:
+				err := k.keep(field, currentField, tag)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// elemOf returns the value that v points to or holds, or an invalid value if
+// v is invalid or nil.
+func elemOf(v reflect.Value) reflect.Value {
+	if !v.IsValid() || v.IsNil() {
+		return reflect.Value{}
+	}
+	return v.Elem()
+}
+
 // Grow a slice by one element (the element takes on the zero value for th slice)
 type operatorGrow struct {
 }
@@ -148,6 +607,13 @@ func (o *operatorGrow) ModifiesPointer() bool {
 	return false
 }
 
+func (o *operatorGrow) checkConstraints(v reflect.Value, tag fieldTag) error {
+	if v.Kind() != reflect.Slice {
+		return nil
+	}
+	return checkAllowed(reflect.Zero(v.Type().Elem()), tag)
+}
+
 func (o *operatorGrow) Do(v reflect.Value) error {
 	switch v.Kind() {
 	case reflect.Slice:
@@ -156,7 +622,7 @@ func (o *operatorGrow) Do(v reflect.Value) error {
 		}
 		v.SetLen(v.Len() + 1)
 	default:
-		return fmt.Errorf("Unable to grow type %v", v.Kind())
+		return newError(ErrInvalidInput, "Unable to grow type %v", v.Kind())
 	}
 	return nil
 }
@@ -180,30 +646,560 @@ func (o *operatorShrink) Do(v reflect.Value) error {
 			v.SetLen(v.Len() - 1)
 		}
 	default:
-		return fmt.Errorf("Unable to shrink type %v", v.Kind())
+		return newError(ErrInvalidInput, "Unable to shrink type %v", v.Kind())
+	}
+	return nil
+}
+
+// The largest length a slice can be resized to in one step, unless it is no
+// more than double the slice's current length. Limits how much memory a
+// single request can allocate while holding the state lock.
+const maxResizeLength = 1 << 16
+
+// Resize a slice to the specified length, appending zero values or
+// truncating it as necessary
+type operatorResize struct {
+	length int
+}
+
+func OperatorResize(length int) Operator {
+	return &operatorResize{length}
+}
+
+func (o *operatorResize) ModifiesPointer() bool {
+	return false
+}
+
+func (o *operatorResize) checkConstraints(v reflect.Value, tag fieldTag) error {
+	if v.Kind() != reflect.Slice || o.length <= v.Len() {
+		return nil
+	}
+	return checkAllowed(reflect.Zero(v.Type().Elem()), tag)
+}
+
+func (o *operatorResize) Do(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		if o.length < 0 {
+			return newError(ErrInvalidInput, "Unable to resize to negative length %d", o.length)
+		}
+		length := v.Len()
+		if o.length > maxResizeLength && o.length > 2*length {
+			return newError(ErrInvalidInput, "Unable to resize to length %d; slices can grow to at most %d or double their length at once",
+				o.length, maxResizeLength)
+		}
+		if o.length > v.Cap() {
+			resized := reflect.MakeSlice(v.Type(), o.length, o.length)
+			reflect.Copy(resized, v)
+			v.Set(resized)
+			return nil
+		}
+		v.SetLen(o.length)
+		// Clear the elements between the old and new lengths: those added
+		// must be zero values, and those removed should not keep anything
+		// they refer to from being garbage collected.
+		clearItems(v.Slice(0, v.Cap()), o.length, length)
+	default:
+		return newError(ErrInvalidInput, "Unable to resize type %v", v.Kind())
+	}
+	return nil
+}
+
+// Remove every element of a slice or every entry of a map
+type operatorClear struct{}
+
+func OperatorClear() Operator {
+	return &operatorClear{}
+}
+
+func (o *operatorClear) ModifiesPointer() bool {
+	return false
+}
+
+func (o *operatorClear) Do(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		clearItems(v, 0, v.Len())
+		v.SetLen(0)
+	case reflect.Map:
+		// Deleting entries by key would miss those with NaN keys
+		v.Clear()
+	default:
+		return newError(ErrInvalidInput, "Unable to clear type %v", v.Kind())
+	}
+	return nil
+}
+
+// clearItems sets the elements of a slice between two indices (in either
+// order) to the zero value.
+func clearItems(v reflect.Value, i int, j int) {
+	if i > j {
+		i, j = j, i
+	}
+	zero := reflect.Zero(v.Type().Elem())
+	for ; i < j; i++ {
+		v.Index(i).Set(zero)
+	}
+}
+
+// Insert a new entry into a map, or a new element into a slice before the
+// element at the index given by key (or at the end, if key is the slice's
+// length). The new value is parsed from a string as with OperatorSet; if the
+// string is empty, it takes on the zero value for the element type.
+type operatorInsert struct {
+	key   string
+	value string
+}
+
+func OperatorInsert(key, value string) Operator {
+	return &operatorInsert{key, value}
+}
+
+func (o *operatorInsert) ModifiesPointer() bool {
+	return false
+}
+
+func (o *operatorInsert) checkConstraints(v reflect.Value, tag fieldTag) error {
+	if v.Kind() != reflect.Map && v.Kind() != reflect.Slice {
+		return nil
+	}
+	newValue, err := o.newValue(v.Type().Elem())
+	if err != nil {
+		return err
+	}
+	return checkAllowed(newValue, tag)
+}
+
+func (o *operatorInsert) Do(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Map:
+		key, err := parseMapKey(o.key, v.Type().Key())
+		if err != nil {
+			return err
+		}
+		if v.MapIndex(key).IsValid() {
+			return newError(ErrConflict, "Map already contains key '%s'", o.key)
+		}
+		newValue, err := o.newValue(v.Type().Elem())
+		if err != nil {
+			return err
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		v.SetMapIndex(key, newValue)
+	case reflect.Slice:
+		index, err := parseIndex(o.key, v.Len()+1)
+		if err != nil {
+			return err
+		}
+		newValue, err := o.newValue(v.Type().Elem())
+		if err != nil {
+			return err
+		}
+		insertItem(v, index, newValue)
+	default:
+		return newError(ErrInvalidInput, "Unable to insert into type %v", v.Kind())
+	}
+	return nil
+}
+
+// newValue returns the value to insert, of the specified type.
+func (o *operatorInsert) newValue(t reflect.Type) (reflect.Value, error) {
+	newValue := reflect.New(t).Elem()
+	if o.value != "" {
+		err := OperatorSet(o.value).Do(newValue)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	return newValue, nil
+}
+
+// insertItem inserts a value into a slice before the element at index (or at
+// the end, if index is the slice's length).
+func insertItem(v reflect.Value, index int, value reflect.Value) {
+	length := v.Len()
+	if length >= v.Cap() {
+		v.Set(doubleCapacity(v))
+	}
+	v.SetLen(length + 1)
+	reflect.Copy(v.Slice(index+1, length+1), v.Slice(index, length))
+	v.Index(index).Set(value)
+}
+
+// Delete an entry from a map (doing nothing if the key is not present), or
+// the element of a slice at the index given by key.
+type operatorDelete struct {
+	key string
+}
+
+func OperatorDelete(key string) Operator {
+	return &operatorDelete{key}
+}
+
+func (o *operatorDelete) ModifiesPointer() bool {
+	return false
+}
+
+func (o *operatorDelete) Do(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Map:
+		key, err := parseMapKey(o.key, v.Type().Key())
+		if err != nil {
+			return err
+		}
+		v.SetMapIndex(key, reflect.Value{})
+	case reflect.Slice:
+		index, err := parseIndex(o.key, v.Len())
+		if err != nil {
+			return err
+		}
+		length := v.Len()
+		reflect.Copy(v.Slice(index, length-1), v.Slice(index+1, length))
+		// Clear the element no longer in the slice, so that anything it
+		// refers to can be garbage collected.
+		v.Index(length - 1).Set(reflect.Zero(v.Type().Elem()))
+		v.SetLen(length - 1)
+	default:
+		return newError(ErrInvalidInput, "Unable to delete from type %v", v.Kind())
+	}
+	return nil
+}
+
+// Insert a deep copy of the element of a slice at an index after it. Nothing
+// the element refers to (through pointers, maps or slices) is shared with
+// the copy.
+type operatorDuplicate struct {
+	index int
+}
+
+func OperatorDuplicate(index int) Operator {
+	return &operatorDuplicate{index}
+}
+
+func (o *operatorDuplicate) ModifiesPointer() bool {
+	return false
+}
+
+func (o *operatorDuplicate) checkConstraints(v reflect.Value, tag fieldTag) error {
+	if v.Kind() != reflect.Slice || checkIndices(v.Len(), o.index) != nil {
+		return nil
+	}
+	return checkAllowed(v.Index(o.index), tag)
+}
+
+func (o *operatorDuplicate) Do(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		err := checkIndices(v.Len(), o.index)
+		if err != nil {
+			return err
+		}
+		duplicate, err := deepCopy(v.Index(o.index))
+		if err != nil {
+			return err
+		}
+		insertItem(v, o.index+1, duplicate)
+	default:
+		return newError(ErrInvalidInput, "Unable to duplicate elements of type %v", v.Kind())
+	}
+	return nil
+}
+
+// Set a pointer (or interface) to nil
+type operatorNil struct{}
+
+func OperatorNil() Operator {
+	return &operatorNil{}
+}
+
+func (o *operatorNil) ModifiesPointer() bool {
+	return true
+}
+
+func (o *operatorNil) Do(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.CanSet() {
+			return newError(ErrReadOnly, "Unable to set unaddressable %v to nil", v.Type())
+		}
+		v.Set(reflect.Zero(v.Type()))
+	default:
+		return newError(ErrInvalidInput, "Unable to set type %v to nil", v.Kind())
+	}
+	return nil
+}
+
+// Point a pointer at a newly-allocated zero value of its element type,
+// replacing anything it previously pointed to
+type operatorNew struct{}
+
+func OperatorNew() Operator {
+	return &operatorNew{}
+}
+
+func (o *operatorNew) ModifiesPointer() bool {
+	return true
+}
+
+func (o *operatorNew) Do(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.CanSet() {
+			return newError(ErrReadOnly, "Unable to allocate unaddressable %v", v.Type())
+		}
+		v.Set(reflect.New(v.Type().Elem()))
+	default:
+		return newError(ErrInvalidInput, "Unable to allocate type %v", v.Kind())
+	}
+	return nil
+}
+
+// Move the element of an array or slice at one index to another, shifting
+// the elements between them along by one
+type operatorMove struct {
+	from int
+	to   int
+}
+
+func OperatorMove(from, to int) Operator {
+	return &operatorMove{from, to}
+}
+
+func (o *operatorMove) ModifiesPointer() bool {
+	return false
+}
+
+func (o *operatorMove) Do(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Array, reflect.Slice:
+		if v.Kind() == reflect.Array && !v.CanAddr() {
+			return newError(ErrReadOnly, "Unable to move elements of unaddressable %v", v.Type())
+		}
+		err := checkIndices(v.Len(), o.from, o.to)
+		if err != nil {
+			return err
+		}
+		moved := reflect.New(v.Type().Elem()).Elem()
+		moved.Set(v.Index(o.from))
+		if o.from < o.to {
+			reflect.Copy(v.Slice(o.from, o.to), v.Slice(o.from+1, o.to+1))
+		} else {
+			reflect.Copy(v.Slice(o.to+1, o.from+1), v.Slice(o.to, o.from))
+		}
+		v.Index(o.to).Set(moved)
+	default:
+		return newError(ErrInvalidInput, "Unable to move elements of type %v", v.Kind())
+	}
+	return nil
+}
+
+// Swap two elements of an array or slice
+type operatorSwap struct {
+	i int
+	j int
+}
+
+func OperatorSwap(i, j int) Operator {
+	return &operatorSwap{i, j}
+}
+
+func (o *operatorSwap) ModifiesPointer() bool {
+	return false
+}
+
+func (o *operatorSwap) Do(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Array, reflect.Slice:
+		if v.Kind() == reflect.Array && !v.CanSet() {
+			return newError(ErrReadOnly, "Unable to swap elements of unaddressable %v", v.Type())
+		}
+		err := checkIndices(v.Len(), o.i, o.j)
+		if err != nil {
+			return err
+		}
+		swapped := reflect.New(v.Type().Elem()).Elem()
+		swapped.Set(v.Index(o.i))
+		v.Index(o.i).Set(v.Index(o.j))
+		v.Index(o.j).Set(swapped)
+	default:
+		return newError(ErrInvalidInput, "Unable to swap elements of type %v", v.Kind())
+	}
+	return nil
+}
+
+// Reset a value to the zero value for its type. Hidden and unexported struct
+// fields inside it keep their values, and the reset is refused if it would
+// change a read-only field or leave an enum field with a value not allowed.
+type operatorZero struct{}
+
+func OperatorZero() Operator {
+	return &operatorZero{}
+}
+
+func (o *operatorZero) ModifiesPointer() bool {
+	return false
+}
+
+func (o *operatorZero) Do(v reflect.Value) error {
+	return o.doTagged(v, fieldTag{})
+}
+
+func (o *operatorZero) doTagged(v reflect.Value, tag fieldTag) error {
+	if !v.CanSet() {
+		return newError(ErrReadOnly, "Unable to reset unaddressable %v", v.Type())
+	}
+	zero := reflect.New(v.Type()).Elem()
+	err := fieldKeeper{format: zeroFormat}.keep(zero, v, tag)
+	if err != nil {
+		return err
+	}
+	v.Set(zero)
+	return nil
+}
+
+// Resetting a struct resets the fields that decoding it from JSON would set,
+// and keeps the rest. Types that decode themselves from text (such as
+// time.Time) are reset as a whole.
+var zeroFormat = snapshotFormat{
+	name: "zero value",
+	sets: func(sf reflect.StructField) bool {
+		return sf.PkgPath == "" || (sf.Anonymous && sf.Type.Kind() == reflect.Struct)
+	},
+	unmarshalers: []reflect.Type{textUnmarshalerType},
+}
+
+// parseIndex converts the string form of an index (as passed to OperatorInsert
+// and OperatorDelete) into an int, checking that it is less than limit.
+func parseIndex(s string, limit int) (int, error) {
+	index, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, newError(ErrInvalidInput, "Unable to use '%s' as an index", s)
+	}
+	err = checkIndices(limit, index)
+	if err != nil {
+		return 0, err
+	}
+	return index, nil
+}
+
+// checkIndices returns an error if any of the indices are out of range for
+// an array or slice of the specified length.
+func checkIndices(length int, indices ...int) error {
+	for _, index := range indices {
+		if index < 0 || index >= length {
+			return newError(ErrNotFound, "Index %d is out of range for length %d", index, length)
+		}
 	}
 	return nil
 }
 
 // doubleCapacity takes a slice (as a Value) and returns a copy of the slice
-// with the capacity doubled
+// with the capacity doubled (or set to one, if the slice has no capacity)
 func doubleCapacity(sliceValue reflect.Value) reflect.Value {
-	newSlice := reflect.MakeSlice(sliceValue.Type(), sliceValue.Len(), 2*sliceValue.Cap())
+	newCap := 2 * sliceValue.Cap()
+	if newCap == 0 {
+		newCap = 1
+	}
+	newSlice := reflect.MakeSlice(sliceValue.Type(), sliceValue.Len(), newCap)
 	reflect.Copy(newSlice, sliceValue)
 	return newSlice
 }
 
 /// end operators
 
-func (e *editor) OperatorFor(values url.Values) (Operator, error) {
-	operatorName := values.Get("operator")
-	switch operatorName {
-	case "set":
-		return OperatorSet(values.Get("value")), nil
-	case "grow":
+func init() {
+	RegisterOperator("set", func(args url.Values) (Operator, error) {
+		return OperatorSet(args.Get("value")), nil
+	}, nil)
+	RegisterOperator("json", func(args url.Values) (Operator, error) {
+		return OperatorSetJSON(args.Get("value")), nil
+	}, nil)
+	RegisterOperator("import", func(args url.Values) (Operator, error) {
+		return OperatorImport(args.Get("format"), []byte(args.Get("value"))), nil
+	}, nil)
+	RegisterOperator("grow", func(args url.Values) (Operator, error) {
 		return OperatorGrow(), nil
-	case "shrink":
+	}, nil)
+	RegisterOperator("shrink", func(args url.Values) (Operator, error) {
 		return OperatorShrink(), nil
+	}, nil)
+	RegisterOperator("resize", func(args url.Values) (Operator, error) {
+		length, err := strconv.Atoi(args.Get("length"))
+		if err != nil {
+			return nil, newError(ErrInvalidInput, "Unable to use '%s' as a length", args.Get("length"))
+		}
+		return OperatorResize(length), nil
+	}, nil)
+	RegisterOperator("clear", func(args url.Values) (Operator, error) {
+		return OperatorClear(), nil
+	}, nil)
+	RegisterOperator("insert", func(args url.Values) (Operator, error) {
+		return OperatorInsert(args.Get("key"), args.Get("value")), nil
+	}, nil)
+	RegisterOperator("delete", func(args url.Values) (Operator, error) {
+		return OperatorDelete(args.Get("key")), nil
+	}, nil)
+	RegisterOperator("duplicate", func(args url.Values) (Operator, error) {
+		index, err := strconv.Atoi(args.Get("index"))
+		if err != nil {
+			return nil, newError(ErrInvalidInput, "Unable to use '%s' as an index", args.Get("index"))
+		}
+		return OperatorDuplicate(index), nil
+	}, nil)
+	RegisterOperator("move", func(args url.Values) (Operator, error) {
+		from, to, err := indexArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		return OperatorMove(from, to), nil
+	}, nil)
+	RegisterOperator("swap", func(args url.Values) (Operator, error) {
+		from, to, err := indexArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		return OperatorSwap(from, to), nil
+	}, nil)
+	RegisterOperator("zero", func(args url.Values) (Operator, error) {
+		return OperatorZero(), nil
+	}, nil)
+	RegisterOperator("nil", func(args url.Values) (Operator, error) {
+		return OperatorNil(), nil
+	}, nil)
+	RegisterOperator("new", func(args url.Values) (Operator, error) {
+		return OperatorNew(), nil
+	}, nil)
+}
+
+// indexArgs parses the "from" and "to" indices passed to the move and swap
+// operators.
+func indexArgs(args url.Values) (int, int, error) {
+	from, err := strconv.Atoi(args.Get("from"))
+	if err != nil {
+		return 0, 0, newError(ErrInvalidInput, "Unable to use '%s' as an index", args.Get("from"))
+	}
+	to, err := strconv.Atoi(args.Get("to"))
+	if err != nil {
+		return 0, 0, newError(ErrInvalidInput, "Unable to use '%s' as an index", args.Get("to"))
+	}
+	return from, to, nil
+}
+
+func (e *editor) OperatorFor(values url.Values) (Operator, error) {
+	operatorName := values.Get("operator")
+	constructor := registeredOperator(operatorName)
+	if constructor == nil {
+		return nil, newError(ErrInvalidInput, "Unable to build Operator named '%s'", operatorName)
+	}
+	operator, err := constructor(values)
+	if err != nil {
+		if statusCode(err) == http.StatusInternalServerError {
+			// Not already categorized, so assume the arguments were bad
+			err = wrapError(ErrInvalidInput, err)
+		}
+		return nil, err
 	}
-	return nil, errors.New("Unable to build Operator named '" + operatorName + "'")
+	return operator, nil
 }
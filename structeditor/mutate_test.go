@@ -15,8 +15,15 @@
 package structeditor
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"net/url"
 	"reflect"
 	"testing"
+	"time"
 )
 
 type equality struct {
@@ -92,7 +99,7 @@ func TestFindValue(t *testing.T) {
 		},
 	}
 
-	e := NewEditor(scanning, "")
+	e := NewEditor(&scanning, "")
 	for _, step := range data {
 		err := e.Mutate(step.path, step.shouldEqual)
 		if err != nil {
@@ -101,6 +108,34 @@ func TestFindValue(t *testing.T) {
 	}
 }
 
+func TestMutateValueState(t *testing.T) {
+	data := struct {
+		Name   string
+		List   []int
+		Counts map[string]int
+	}{"a", []int{1, 2}, map[string]int{"a": 1}}
+	e := NewEditor(data, "")
+
+	steps := []struct {
+		path     string
+		operator Operator
+	}{
+		{"Name", OperatorSet("b")},
+		{"List", OperatorGrow()},
+		{"List", OperatorResize(5)},
+		{"List", OperatorMove(0, 1)},
+		{"Counts", OperatorInsert("b", "2")},
+	}
+	for _, step := range steps {
+		if err := e.Mutate(step.path, step.operator); !errors.Is(err, ErrReadOnly) {
+			t.Error(step.path, "- expected", ErrReadOnly, "saw", err)
+		}
+	}
+	if data.List[0] != 1 || len(data.Counts) != 1 {
+		t.Error("Expected shared values to be unchanged, saw", data)
+	}
+}
+
 type modify struct {
 	Foo int
 	Bar string
@@ -203,3 +238,1052 @@ func TestOperatorShrink(t *testing.T) {
 		t.Error("Expected", target, "saw", data)
 	}
 }
+
+type mapHolder struct {
+	Counts  map[string]int
+	Configs map[int]testEmployee
+}
+
+func TestModifyMapValue(t *testing.T) {
+	data := mapHolder{
+		Counts: map[string]int{
+			"a":   1,
+			"b.c": 2,
+		},
+		Configs: map[int]testEmployee{
+			7: {Name: "Bob", Id: "A"},
+		},
+	}
+
+	target := mapHolder{
+		Counts: map[string]int{
+			"a":   5,
+			"b.c": 6,
+		},
+		Configs: map[int]testEmployee{
+			7: {Name: "Sue", Id: "A"},
+		},
+	}
+
+	mutations := []struct {
+		path     string
+		newValue string
+	}{
+		{"Counts.a", "5"},
+		{"Counts.b%2Ec", "6"},
+		{"Configs.7.Name", "Sue"},
+	}
+
+	e := NewEditor(&data, "")
+
+	for _, mutation := range mutations {
+		err := e.Mutate(
+			mutation.path,
+			OperatorSet(mutation.newValue))
+		if err != nil {
+			t.Error(mutation.path, "-", err)
+		}
+	}
+
+	if !reflect.DeepEqual(data, target) {
+		t.Error("Expected", target, "saw", data)
+	}
+
+	err := e.Mutate("Counts.missing", OperatorSet("1"))
+	if err == nil {
+		t.Error("Expected error setting missing map entry")
+	}
+}
+
+func TestOperatorInsertDelete(t *testing.T) {
+	data := mapHolder{
+		Counts: map[string]int{
+			"a": 1,
+		},
+	}
+
+	target := mapHolder{
+		Counts: map[string]int{
+			"b": 2,
+		},
+		Configs: map[int]testEmployee{
+			3: {},
+		},
+	}
+
+	e := NewEditor(&data, "")
+	steps := []struct {
+		path     string
+		operator Operator
+	}{
+		{"Counts", OperatorInsert("b", "2")},
+		{"Counts", OperatorDelete("a")},
+		{"Configs", OperatorInsert("3", "")},
+	}
+	for _, step := range steps {
+		err := e.Mutate(step.path, step.operator)
+		if err != nil {
+			t.Error(step.path, "-", err)
+		}
+	}
+
+	if !reflect.DeepEqual(data, target) {
+		t.Error("Expected", target, "saw", data)
+	}
+
+	err := e.Mutate("Counts", OperatorInsert("b", "3"))
+	if err == nil {
+		t.Error("Expected error inserting duplicate key")
+	}
+	err = e.Mutate("Configs", OperatorInsert("x", ""))
+	if err == nil {
+		t.Error("Expected error inserting unparseable key")
+	}
+}
+
+type interfaceHolder struct {
+	Held    interface{}
+	Pointed interface{}
+	Number  interface{}
+	Missing interface{}
+}
+
+func TestModifyInterfaceContents(t *testing.T) {
+	data := interfaceHolder{
+		Held:    testEmployee{Name: "Bob", Id: "A"},
+		Pointed: &testEmployee{Name: "Sue", Id: "B"},
+		Number:  5,
+	}
+
+	target := interfaceHolder{
+		Held:    testEmployee{Name: "Robert", Id: "A"},
+		Pointed: &testEmployee{Name: "Susan", Id: "B"},
+		Number:  7,
+	}
+
+	mutations := []struct {
+		path     string
+		newValue string
+	}{
+		{"Held.Name", "Robert"},
+		{"Pointed.Name", "Susan"},
+		{"Number", "7"},
+	}
+
+	e := NewEditor(&data, "")
+
+	for _, mutation := range mutations {
+		err := e.Mutate(
+			mutation.path,
+			OperatorSet(mutation.newValue))
+		if err != nil {
+			t.Error(mutation.path, "-", err)
+		}
+	}
+
+	if !reflect.DeepEqual(data, target) {
+		t.Error("Expected", target, "saw", data)
+	}
+
+	err := e.Mutate("Missing", OperatorSet("1"))
+	if err == nil {
+		t.Error("Expected error setting inside nil interface")
+	}
+}
+
+type privateHolder struct {
+	Public  int
+	private int
+	hidden  struct {
+		Inner string
+	}
+	counts map[string]int
+}
+
+func TestModifyUnexported(t *testing.T) {
+	data := privateHolder{
+		counts: map[string]int{"a": 1},
+	}
+
+	e := NewEditor(&data, "")
+	for _, path := range []string{"private", "hidden.Inner", "counts.a"} {
+		if err := e.Mutate(path, OperatorSet("5")); err == nil {
+			t.Error(path, "- expected error mutating unexported field")
+		}
+	}
+
+	e = NewEditor(&data, "", WithUnexportedMutation())
+	mutations := []struct {
+		path     string
+		newValue string
+	}{
+		{"private", "7"},
+		{"hidden.Inner", "hi"},
+		{"counts.a", "3"},
+	}
+	for _, mutation := range mutations {
+		err := e.Mutate(mutation.path, OperatorSet(mutation.newValue))
+		if err != nil {
+			t.Error(mutation.path, "-", err)
+		}
+	}
+	if data.private != 7 || data.hidden.Inner != "hi" || data.counts["a"] != 3 {
+		t.Error("Expected unexported fields to change, saw", data)
+	}
+
+	e = NewEditor(data, "", WithUnexportedMutation())
+	if err := e.Mutate("private", OperatorSet("8")); err == nil {
+		t.Error("Expected error mutating unaddressable unexported field")
+	}
+}
+
+type taggedHolder struct {
+	Hidden   int    `structeditor:"-"`
+	Fixed    int    `structeditor:"readonly"`
+	Status   string `structeditor:"enum=open|closed"`
+	Settings struct {
+		Level int
+	} `structeditor:"readonly"`
+}
+
+func TestModifyTagged(t *testing.T) {
+	data := taggedHolder{
+		Status: "open",
+	}
+
+	e := NewEditor(&data, "")
+	refused := []struct {
+		path     string
+		newValue string
+	}{
+		{"Hidden", "1"},
+		{"Fixed", "1"},
+		{"Settings.Level", "1"},
+		{"Status", "pending"},
+	}
+	for _, mutation := range refused {
+		err := e.Mutate(mutation.path, OperatorSet(mutation.newValue))
+		if err == nil {
+			t.Error(mutation.path, "- expected mutation to be refused")
+		}
+	}
+
+	if err := e.Mutate("Status", OperatorSet("closed")); err != nil {
+		t.Error("Status -", err)
+	}
+
+	target := taggedHolder{
+		Status: "closed",
+	}
+	if !reflect.DeepEqual(data, target) {
+		t.Error("Expected", target, "saw", data)
+	}
+}
+
+type color string
+
+func (c color) EnumValues() []string {
+	return []string{"red", "green", "blue"}
+}
+
+type enumElements struct {
+	Levels []string          `structeditor:"enum=a|b"`
+	ByName map[string]string `structeditor:"enum=a|b"`
+	Hues   []color
+	Nested []struct {
+		Level string `structeditor:"enum=a|b"`
+	}
+}
+
+func TestModifyEnumElements(t *testing.T) {
+	data := enumElements{
+		Levels: []string{"a"},
+		ByName: map[string]string{"x": "a"},
+		Hues:   []color{"red"},
+	}
+	e := NewEditor(&data, "")
+
+	refused := []struct {
+		path     string
+		operator Operator
+	}{
+		{"Levels", OperatorInsert("0", "zzz")},
+		{"Levels", OperatorGrow()},
+		{"Levels", OperatorResize(2)},
+		{"Levels", OperatorSetJSON(`["q"]`)},
+		{"ByName", OperatorInsert("y", "zzz")},
+		{"ByName", OperatorSetJSON(`{"x": "q"}`)},
+		{"Hues", OperatorInsert("0", "purple")},
+		{"Hues", OperatorGrow()},
+		{"Hues", OperatorSetJSON(`["purple"]`)},
+		{"Nested", OperatorSetJSON(`[{"Level": "q"}]`)},
+		{"", OperatorImport("json", []byte(`{"Levels": ["q"]}`))},
+	}
+	for _, step := range refused {
+		if err := e.Mutate(step.path, step.operator); !errors.Is(err, ErrInvalidInput) {
+			t.Error(step.path, step.operator, "- expected", ErrInvalidInput, "saw", err)
+		}
+	}
+
+	mutations := []struct {
+		path     string
+		operator Operator
+	}{
+		{"Levels", OperatorInsert("1", "b")},
+		{"Levels", OperatorDuplicate(0)},
+		{"ByName", OperatorSetJSON(`{"x": "a", "y": "b"}`)},
+		{"Hues", OperatorInsert("1", "blue")},
+		{"Nested", OperatorSetJSON(`[{"Level": "b"}]`)},
+	}
+	for _, step := range mutations {
+		if err := e.Mutate(step.path, step.operator); err != nil {
+			t.Error(step.path, step.operator, "-", err)
+		}
+	}
+	if !reflect.DeepEqual(data.Levels, []string{"a", "a", "b"}) ||
+		!reflect.DeepEqual(data.ByName, map[string]string{"x": "a", "y": "b"}) ||
+		!reflect.DeepEqual(data.Hues, []color{"red", "blue"}) ||
+		len(data.Nested) != 1 || data.Nested[0].Level != "b" {
+		t.Error("Expected allowed values only, saw", data)
+	}
+}
+
+type widgetHolder struct {
+	Small int8
+	Hue   color
+	When  time.Time
+}
+
+func TestModifyWidgetTypes(t *testing.T) {
+	data := widgetHolder{
+		Small: 1,
+		Hue:   "red",
+		When:  time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	target := widgetHolder{
+		Small: -128,
+		Hue:   "blue",
+		When:  time.Date(2024, 1, 2, 15, 4, 0, 0, time.UTC),
+	}
+
+	e := NewEditor(&data, "")
+	refused := []struct {
+		path     string
+		newValue string
+	}{
+		{"Small", "128"},
+		{"Hue", "purple"},
+		{"When", "yesterday"},
+	}
+	for _, mutation := range refused {
+		err := e.Mutate(mutation.path, OperatorSet(mutation.newValue))
+		if !errors.Is(err, ErrInvalidInput) {
+			t.Error(mutation.path, "- expected invalid input, saw", err)
+		}
+	}
+
+	mutations := []struct {
+		path     string
+		newValue string
+	}{
+		{"Small", "-128"},
+		{"Hue", "blue"},
+		{"When", "2024-01-02T15:04"},
+	}
+	for _, mutation := range mutations {
+		err := e.Mutate(mutation.path, OperatorSet(mutation.newValue))
+		if err != nil {
+			t.Error(mutation.path, "-", err)
+		}
+	}
+	if !reflect.DeepEqual(data, target) {
+		t.Error("Expected", target, "saw", data)
+	}
+
+	if err := e.Mutate("When", OperatorSet("2024-01-02T15:04:05+02:00")); err != nil {
+		t.Error("When -", err)
+	}
+	if !data.When.Equal(time.Date(2024, 1, 2, 13, 4, 5, 0, time.UTC)) {
+		t.Error("Expected RFC 3339 time to be parsed, saw", data.When)
+	}
+}
+
+type pointerHolder struct {
+	Number  *int
+	Manager *testEmployee
+	Held    interface{}
+	Nested  **int
+}
+
+func TestPointerOperators(t *testing.T) {
+	five := 5
+	fivePtr := &five
+	data := pointerHolder{
+		Manager: &testEmployee{Name: "Bob", Id: "A"},
+		Held:    3,
+		Nested:  &fivePtr,
+	}
+
+	e := NewEditor(&data, "")
+	mutations := []struct {
+		path     string
+		operator Operator
+	}{
+		{"Number", OperatorNew()},
+		{"Number", OperatorSet("7")},
+		{"Manager", OperatorNil()},
+		{"Held", OperatorNil()},
+		{"Nested", OperatorSet("6")},
+	}
+	for _, mutation := range mutations {
+		err := e.Mutate(mutation.path, mutation.operator)
+		if err != nil {
+			t.Error(mutation.path, "-", err)
+		}
+	}
+
+	if data.Number == nil || *data.Number != 7 {
+		t.Error("Expected Number to point to 7, saw", data.Number)
+	}
+	if data.Manager != nil {
+		t.Error("Expected Manager to be nil, saw", data.Manager)
+	}
+	if data.Held != nil {
+		t.Error("Expected Held to be nil, saw", data.Held)
+	}
+	if five != 6 {
+		t.Error("Expected Nested to point to 6, saw", five)
+	}
+
+	err := e.Mutate("Manager.Name", OperatorSet("Sue"))
+	if !errors.Is(err, ErrNotFound) {
+		t.Error("Expected ErrNotFound setting through nil pointer, saw", err)
+	}
+	err = e.Mutate("", OperatorNil())
+	if !errors.Is(err, ErrReadOnly) {
+		t.Error("Expected ErrReadOnly clearing the state pointer, saw", err)
+	}
+	err = e.Mutate("Held", OperatorNew())
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Error("Expected ErrInvalidInput allocating an interface, saw", err)
+	}
+}
+
+func TestSetThroughStatePointer(t *testing.T) {
+	data := 1
+	e := NewEditor(&data, "")
+	err := e.Mutate("", OperatorSet("2"))
+	if err != nil {
+		t.Error("Could not set state -", err)
+	}
+	if data != 2 {
+		t.Error("Expected", 2, "saw", data)
+	}
+}
+
+type complexHolder struct {
+	Small complex64
+	Large complex128
+}
+
+func TestComplexRoundTrip(t *testing.T) {
+	values := []complex128{
+		complex(1, 2),
+		complex(-0.5, 0),
+		complex(0, -3.25),
+		complex(1e300, 1e-300),
+	}
+
+	for _, value := range values {
+		data := complexHolder{Small: complex64(value), Large: value}
+		var saved complexHolder
+		e := NewEditor(&saved, "")
+		v := reflect.ValueOf(data)
+		for _, field := range []string{"Small", "Large"} {
+			text, ok := formatScalar(v.FieldByName(field))
+			if !ok {
+				t.Error(field, "- expected complex to be a scalar")
+			}
+			if err := e.Mutate(field, OperatorSet(text)); err != nil {
+				t.Error(field, "-", err)
+			}
+		}
+		if !reflect.DeepEqual(data, saved) {
+			t.Error("Expected", data, "saw", saved)
+		}
+	}
+
+	data := complexHolder{}
+	e := NewEditor(&data, "")
+	if err := e.Mutate("Large", OperatorSet("3-4i")); err != nil || data.Large != complex(3, -4) {
+		t.Error("Expected 3-4i to be parsed, saw", data.Large, err)
+	}
+	refused := []struct {
+		path     string
+		newValue string
+	}{
+		{"Large", "one"},
+		{"Small", "(1e300+0i)"},
+	}
+	for _, mutation := range refused {
+		err := e.Mutate(mutation.path, OperatorSet(mutation.newValue))
+		if !errors.Is(err, ErrInvalidInput) {
+			t.Error(mutation.path, "- expected invalid input, saw", err)
+		}
+	}
+}
+
+type wellKnownHolder struct {
+	Timeout time.Duration
+	Balance big.Int
+	Limit   *big.Int
+	Address net.IP
+	Site    url.URL
+}
+
+func TestModifyWellKnownTypes(t *testing.T) {
+	data := wellKnownHolder{Limit: big.NewInt(1)}
+	e := NewEditor(&data, "")
+
+	mutations := []struct {
+		path     string
+		newValue string
+	}{
+		{"Timeout", "1m30s"},
+		{"Balance", "-123456789012345678901234567890"},
+		{"Limit", "0x10"},
+		{"Address", "192.168.0.1"},
+		{"Site", "https://example.com/a?b=c"},
+	}
+	for _, mutation := range mutations {
+		err := e.Mutate(mutation.path, OperatorSet(mutation.newValue))
+		if err != nil {
+			t.Error(mutation.path, "-", err)
+		}
+	}
+
+	balance, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	if data.Timeout != 90*time.Second {
+		t.Error("Expected", 90*time.Second, "saw", data.Timeout)
+	}
+	if data.Balance.Cmp(balance) != 0 {
+		t.Error("Expected", balance, "saw", &data.Balance)
+	}
+	if data.Limit.Int64() != 16 {
+		t.Error("Expected", 16, "saw", data.Limit)
+	}
+	if !data.Address.Equal(net.IPv4(192, 168, 0, 1)) {
+		t.Error("Expected 192.168.0.1, saw", data.Address)
+	}
+	if data.Site.Host != "example.com" || data.Site.RawQuery != "b=c" {
+		t.Error("Expected https://example.com/a?b=c, saw", data.Site.String())
+	}
+
+	refused := []struct {
+		path     string
+		newValue string
+	}{
+		{"Timeout", "90"},
+		{"Balance", "lots"},
+		{"Address", "192.168.0.256"},
+		{"Site", "http://[::1"},
+	}
+	for _, mutation := range refused {
+		err := e.Mutate(mutation.path, OperatorSet(mutation.newValue))
+		if !errors.Is(err, ErrInvalidInput) {
+			t.Error(mutation.path, "- expected invalid input, saw", err)
+		}
+	}
+}
+
+// A type edited through its text form
+type userID struct {
+	number int
+}
+
+func (u userID) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("user-%d", u.number)), nil
+}
+
+func (u *userID) UnmarshalText(text []byte) error {
+	_, err := fmt.Sscanf(string(text), "user-%d", &u.number)
+	return err
+}
+
+// A type that can be shown in its text form but not parsed
+type checksum [2]byte
+
+func (c checksum) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%x", c[:])), nil
+}
+
+// A type with a description
+type level int
+
+func (l level) String() string {
+	if l > 5 {
+		return "High"
+	}
+	return "Low"
+}
+
+type textHolder struct {
+	Owner  userID
+	Users  map[userID]bool
+	Sum    checksum
+	Volume level
+}
+
+func TestModifyTextTypes(t *testing.T) {
+	data := textHolder{
+		Owner: userID{1},
+		Users: map[userID]bool{{2}: false},
+	}
+	e := NewEditor(&data, "")
+
+	mutations := []struct {
+		path     string
+		operator Operator
+	}{
+		{"Owner", OperatorSet("user-7")},
+		{"Users.user-2", OperatorSet("true")},
+		{"Users", OperatorInsert("user-3", "true")},
+		{"Volume", OperatorSet("9")},
+	}
+	for _, mutation := range mutations {
+		err := e.Mutate(mutation.path, mutation.operator)
+		if err != nil {
+			t.Error(mutation.path, "-", err)
+		}
+	}
+	target := textHolder{
+		Owner:  userID{7},
+		Users:  map[userID]bool{{2}: true, {3}: true},
+		Volume: 9,
+	}
+	if !reflect.DeepEqual(data, target) {
+		t.Error("Expected", target, "saw", data)
+	}
+
+	err := e.Mutate("Owner", OperatorSet("admin"))
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Error("Expected invalid input, saw", err)
+	}
+	err = e.Mutate("Sum", OperatorSet("ffff"))
+	if !errors.Is(err, ErrReadOnly) {
+		t.Error("Expected read-only, saw", err)
+	}
+}
+
+func TestSliceIndexOperators(t *testing.T) {
+	data := []struct {
+		operator Operator
+		result   []int
+	}{
+		{OperatorInsert("0", "9"), []int{9, 1, 2, 3, 4}},
+		{OperatorInsert("2", ""), []int{1, 2, 0, 3, 4}},
+		{OperatorInsert("4", "5"), []int{1, 2, 3, 4, 5}},
+		{OperatorDelete("0"), []int{2, 3, 4}},
+		{OperatorDelete("3"), []int{1, 2, 3}},
+		{OperatorMove(0, 3), []int{2, 3, 4, 1}},
+		{OperatorMove(3, 1), []int{1, 4, 2, 3}},
+		{OperatorMove(2, 2), []int{1, 2, 3, 4}},
+		{OperatorSwap(0, 3), []int{4, 2, 3, 1}},
+		{OperatorDuplicate(1), []int{1, 2, 2, 3, 4}},
+		{OperatorDuplicate(3), []int{1, 2, 3, 4, 4}},
+	}
+	for _, step := range data {
+		holder := growable{Bar: []int{1, 2, 3, 4}}
+		e := NewEditor(&holder, "")
+		err := e.Mutate("Bar", step.operator)
+		if err != nil {
+			t.Error("Could not mutate Bar -", err)
+		}
+		if !reflect.DeepEqual(holder.Bar, step.result) {
+			t.Error("Expected", step.result, "saw", holder.Bar)
+		}
+	}
+
+	array := [4]int{1, 2, 3, 4}
+	e := NewEditor(&array, "")
+	if err := e.Mutate("", OperatorMove(3, 0)); err != nil {
+		t.Error("Could not move array element -", err)
+	}
+	if err := e.Mutate("", OperatorSwap(1, 2)); err != nil {
+		t.Error("Could not swap array elements -", err)
+	}
+	if array != [4]int{4, 2, 1, 3} {
+		t.Error("Expected", [4]int{4, 2, 1, 3}, "saw", array)
+	}
+
+	holder := growable{Bar: []int{1, 2}}
+	e = NewEditor(&holder, "")
+	refused := []struct {
+		operator Operator
+		kind     error
+	}{
+		{OperatorInsert("3", ""), ErrNotFound},
+		{OperatorInsert("-1", ""), ErrNotFound},
+		{OperatorInsert("first", ""), ErrInvalidInput},
+		{OperatorInsert("0", "one"), ErrInvalidInput},
+		{OperatorDelete("2"), ErrNotFound},
+		{OperatorMove(0, 2), ErrNotFound},
+		{OperatorSwap(-1, 0), ErrNotFound},
+		{OperatorDuplicate(2), ErrNotFound},
+	}
+	for _, step := range refused {
+		err := e.Mutate("Bar", step.operator)
+		if !errors.Is(err, step.kind) {
+			t.Error("Expected", step.kind, "saw", err)
+		}
+	}
+	if !reflect.DeepEqual(holder.Bar, []int{1, 2}) {
+		t.Error("Expected failed operators to leave slice unchanged, saw", holder.Bar)
+	}
+}
+
+func TestResizeOperators(t *testing.T) {
+	data := []struct {
+		operator Operator
+		result   []int
+	}{
+		{OperatorResize(6), []int{1, 2, 3, 4, 0, 0}},
+		{OperatorResize(2), []int{1, 2}},
+		{OperatorResize(4), []int{1, 2, 3, 4}},
+		{OperatorResize(0), []int{}},
+		{OperatorClear(), []int{}},
+	}
+	for _, step := range data {
+		holder := growable{Bar: []int{1, 2, 3, 4}}
+		e := NewEditor(&holder, "")
+		err := e.Mutate("Bar", step.operator)
+		if err != nil {
+			t.Error("Could not mutate Bar -", err)
+		}
+		if !reflect.DeepEqual(holder.Bar, step.result) {
+			t.Error("Expected", step.result, "saw", holder.Bar)
+		}
+	}
+
+	// Growing within the slice's capacity must not bring back the elements
+	// removed by shrinking it.
+	holder := growable{Bar: []int{1, 2, 3, 4}}
+	e := NewEditor(&holder, "")
+	if err := e.Mutate("Bar", OperatorResize(1)); err != nil {
+		t.Error("Could not shrink Bar -", err)
+	}
+	if err := e.Mutate("Bar", OperatorResize(3)); err != nil {
+		t.Error("Could not grow Bar -", err)
+	}
+	if !reflect.DeepEqual(holder.Bar, []int{1, 0, 0}) {
+		t.Error("Expected", []int{1, 0, 0}, "saw", holder.Bar)
+	}
+	for _, length := range []int{-1, maxResizeLength + 1, 100000000000000} {
+		if err := e.Mutate("Bar", OperatorResize(length)); !errors.Is(err, ErrInvalidInput) {
+			t.Error(length, "- expected", ErrInvalidInput, "saw", err)
+		}
+	}
+	if err := e.Mutate("Bar", OperatorResize(maxResizeLength)); err != nil || len(holder.Bar) != maxResizeLength {
+		t.Error("Could not grow Bar to", maxResizeLength, "-", err)
+	}
+	if err := e.Mutate("Bar", OperatorResize(2*maxResizeLength)); err != nil || len(holder.Bar) != 2*maxResizeLength {
+		t.Error("Could not double Bar -", err)
+	}
+	if err := e.Mutate("Foo", OperatorClear()); !errors.Is(err, ErrInvalidInput) {
+		t.Error("Expected", ErrInvalidInput, "saw", err)
+	}
+
+	maps := mapHolder{Counts: map[string]int{"a": 1, "b": 2}}
+	e = NewEditor(&maps, "")
+	if err := e.Mutate("Counts", OperatorClear()); err != nil {
+		t.Error("Could not clear Counts -", err)
+	}
+	if len(maps.Counts) != 0 || maps.Counts == nil {
+		t.Error("Expected empty map, saw", maps.Counts)
+	}
+
+	floats := map[float64]int{math.NaN(): 1, 2: 2}
+	e = NewEditor(&floats, "")
+	if err := e.Mutate("", OperatorClear()); err != nil || len(floats) != 0 {
+		t.Error("Expected NaN keys to be cleared, saw", floats, err)
+	}
+}
+
+func TestOperatorZero(t *testing.T) {
+	data := mapHolder{
+		Counts:  map[string]int{"a": 1},
+		Configs: map[int]testEmployee{1: {Name: "Alice", Id: "A"}},
+	}
+	target := mapHolder{
+		Counts:  map[string]int{"a": 0},
+		Configs: map[int]testEmployee{1: {}},
+	}
+
+	e := NewEditor(&data, "")
+	for _, path := range []string{"Counts.a", "Configs.1"} {
+		if err := e.Mutate(path, OperatorZero()); err != nil {
+			t.Error(path, "-", err)
+		}
+	}
+	if !reflect.DeepEqual(data, target) {
+		t.Error("Expected", target, "saw", data)
+	}
+
+	tagged := taggedHolder{Status: "open"}
+	e = NewEditor(&tagged, "")
+	for _, path := range []string{"Status", "Fixed"} {
+		if err := e.Mutate(path, OperatorZero()); err == nil {
+			t.Error(path, "- expected reset to be refused")
+		}
+	}
+	widgets := widgetHolder{Small: 3, Hue: "red"}
+	e = NewEditor(&widgets, "")
+	if err := e.Mutate("Hue", OperatorZero()); !errors.Is(err, ErrInvalidInput) {
+		t.Error("Expected", ErrInvalidInput, "saw", err)
+	}
+	// Resetting the whole struct would leave Hue with a value not allowed
+	if err := e.Mutate("", OperatorZero()); !errors.Is(err, ErrInvalidInput) {
+		t.Error("Expected", ErrInvalidInput, "saw", err)
+	}
+	if widgets.Hue != "red" || widgets.Small != 3 {
+		t.Error("Expected refused reset to leave state unchanged, saw", widgets)
+	}
+
+	protected := zeroHolder{
+		Name:     "x",
+		When:     time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC),
+		Hidden:   1,
+		internal: 2,
+	}
+	e = NewEditor(&protected, "")
+	if err := e.Mutate("", OperatorZero()); err != nil {
+		t.Error("Could not reset state -", err)
+	}
+	expected := zeroHolder{Hidden: 1, internal: 2}
+	if !reflect.DeepEqual(protected, expected) {
+		t.Error("Expected", expected, "saw", protected)
+	}
+	protected.Fixed = 3
+	if err := e.Mutate("", OperatorZero()); !errors.Is(err, ErrReadOnly) {
+		t.Error("Expected", ErrReadOnly, "saw", err)
+	}
+	if protected.Fixed != 3 {
+		t.Error("Expected refused reset to leave Fixed unchanged, saw", protected.Fixed)
+	}
+}
+
+type zeroHolder struct {
+	Name     string
+	When     time.Time
+	Hidden   int `structeditor:"-"`
+	Fixed    int `structeditor:"readonly"`
+	internal int
+}
+
+type duplicated struct {
+	Tags     []string
+	Counts   map[string]int
+	Boss     *testEmployee
+	Self     *duplicated
+	Any      interface{}
+	internal []int
+}
+
+func TestOperatorDuplicate(t *testing.T) {
+	original := &duplicated{
+		Tags:     []string{"a"},
+		Counts:   map[string]int{"a": 1},
+		Boss:     &testEmployee{Name: "Bob"},
+		Any:      []int{1},
+		internal: []int{2},
+	}
+	original.Self = original
+	data := struct{ Items []*duplicated }{[]*duplicated{original}}
+
+	e := NewEditor(&data, "")
+	if err := e.Mutate("Items", OperatorDuplicate(0)); err != nil {
+		t.Fatal("Could not duplicate element -", err)
+	}
+	if len(data.Items) != 2 || data.Items[0] != original {
+		t.Fatal("Expected copy to be inserted after original, saw", data.Items)
+	}
+	duplicate := data.Items[1]
+	if duplicate == original || duplicate.Self != duplicate {
+		t.Error("Expected copy to refer to itself, saw", duplicate.Self)
+	}
+	if !reflect.DeepEqual(duplicate, original) {
+		t.Error("Expected", original, "saw", duplicate)
+	}
+
+	duplicate.Tags[0] = "b"
+	duplicate.Counts["a"] = 2
+	duplicate.Boss.Name = "Alice"
+	duplicate.Any.([]int)[0] = 3
+	duplicate.internal[0] = 4
+	if original.Tags[0] != "a" || original.Counts["a"] != 1 || original.Boss.Name != "Bob" ||
+		original.Any.([]int)[0] != 1 || original.internal[0] != 2 {
+		t.Error("Expected changing the copy to leave the original unchanged, saw", original)
+	}
+}
+
+type pastedStruct struct {
+	Name     string
+	Tags     []string
+	Counts   map[string]int
+	Boss     *testEmployee
+	Status   string `structeditor:"enum=open|closed"`
+	Created  string `structeditor:"readonly"`
+	Hidden   int    `structeditor:"-"`
+	internal int
+}
+
+func TestOperatorSetJSON(t *testing.T) {
+	data := pastedStruct{
+		Name:     "old",
+		Tags:     []string{"a", "b"},
+		Counts:   map[string]int{"a": 1},
+		Status:   "open",
+		Created:  "today",
+		Hidden:   1,
+		internal: 2,
+	}
+	target := pastedStruct{
+		Name:     "new",
+		Tags:     []string{"c"},
+		Counts:   map[string]int{"b": 2},
+		Boss:     &testEmployee{Name: "Bob"},
+		Status:   "closed",
+		Created:  "today",
+		Hidden:   1,
+		internal: 2,
+	}
+
+	e := NewEditor(&data, "")
+	err := e.Mutate("", OperatorSetJSON(`{"Name": "new", "Tags": ["c"], "Counts": {"b": 2},
+		"Boss": {"Name": "Bob"}, "Status": "closed", "Created": "today"}`))
+	if err != nil {
+		t.Error("Could not set from JSON -", err)
+	}
+	if !reflect.DeepEqual(data, target) {
+		t.Error("Expected", target, "saw", data)
+	}
+
+	if err := e.Mutate("Tags", OperatorSetJSON(`["x", "y"]`)); err != nil {
+		t.Error("Could not set Tags from JSON -", err)
+	}
+	if !reflect.DeepEqual(data.Tags, []string{"x", "y"}) {
+		t.Error("Expected", []string{"x", "y"}, "saw", data.Tags)
+	}
+
+	refused := []struct {
+		path     string
+		document string
+		kind     error
+	}{
+		{"", `{"Unknown": 1}`, ErrInvalidInput},
+		{"", `{"Name": 1}`, ErrInvalidInput},
+		{"", `{"Name": "x"} {}`, ErrInvalidInput},
+		{"", `{"Status": "pending", "Created": "today"}`, ErrInvalidInput},
+		{"", `{"Status": "open", "Created": "tomorrow"}`, ErrReadOnly},
+		{"Counts", `{"a": "one"}`, ErrInvalidInput},
+		{"Name", `"x"`, ErrInvalidInput},
+	}
+	for _, step := range refused {
+		err := e.Mutate(step.path, OperatorSetJSON(step.document))
+		if !errors.Is(err, step.kind) {
+			t.Error(step.document, "- expected", step.kind, "saw", err)
+		}
+	}
+	if data.Name != "new" || data.Counts["b"] != 2 {
+		t.Error("Expected refused documents to leave state unchanged, saw", data)
+	}
+}
+
+type pastedAccount struct {
+	Name     string
+	Created  string `structeditor:"readonly"`
+	Hidden   int    `structeditor:"-"`
+	internal int
+}
+
+type pastedAccounts struct {
+	Accounts []pastedAccount
+	ByName   map[string]pastedAccount
+}
+
+func TestOperatorSetJSONElements(t *testing.T) {
+	data := pastedAccounts{
+		Accounts: []pastedAccount{{Name: "a", Created: "today", Hidden: 1, internal: 2}},
+		ByName:   map[string]pastedAccount{"b": {Name: "b", Created: "today", Hidden: 3, internal: 4}},
+	}
+	e := NewEditor(&data, "")
+
+	refused := []struct {
+		path     string
+		document string
+	}{
+		{"Accounts", `[{"Name": "a", "Created": "CHANGED"}]`},
+		{"ByName", `{"b": {"Name": "b", "Created": "CHANGED"}}`},
+		{"", `{"Accounts": [{"Name": "a", "Created": "CHANGED"}]}`},
+	}
+	for _, step := range refused {
+		err := e.Mutate(step.path, OperatorSetJSON(step.document))
+		if !errors.Is(err, ErrReadOnly) {
+			t.Error(step.document, "- expected", ErrReadOnly, "saw", err)
+		}
+	}
+	if data.Accounts[0].Created != "today" || data.ByName["b"].Created != "today" {
+		t.Error("Expected read-only fields to be unchanged, saw", data)
+	}
+
+	err := e.Mutate("", OperatorSetJSON(`{"Accounts": [{"Name": "x", "Created": "today"}],
+		"ByName": {"b": {"Name": "y", "Created": "today"}}}`))
+	if err != nil {
+		t.Error("Could not set from JSON -", err)
+	}
+	target := pastedAccounts{
+		Accounts: []pastedAccount{{Name: "x", Created: "today", Hidden: 1, internal: 2}},
+		ByName:   map[string]pastedAccount{"b": {Name: "y", Created: "today", Hidden: 3, internal: 4}},
+	}
+	if !reflect.DeepEqual(data, target) {
+		t.Error("Expected", target, "saw", data)
+	}
+}
+
+func TestOperatorSetJSONPointers(t *testing.T) {
+	data := pointerHolder{Held: testEmployee{Name: "Carol"}}
+	e := NewEditor(&data, "")
+
+	// Nil pointers are pointed at the decoded value
+	if err := e.Mutate("Manager", OperatorSetJSON(`{"Name": "Bob"}`)); err != nil {
+		t.Error("Could not set nil pointer from JSON -", err)
+	}
+	if data.Manager == nil || *data.Manager != (testEmployee{Name: "Bob"}) {
+		t.Error("Expected Manager to be Bob, saw", data.Manager)
+	}
+
+	// Other pointers keep pointing at the same value, which is replaced
+	manager := data.Manager
+	if err := e.Mutate("Manager", OperatorSetJSON(`{"Name": "Alice", "Id": "A"}`)); err != nil {
+		t.Error("Could not set pointer from JSON -", err)
+	}
+	if data.Manager != manager || *manager != (testEmployee{Name: "Alice", Id: "A"}) {
+		t.Error("Expected Manager to be replaced in place, saw", data.Manager)
+	}
+
+	// Interfaces keep the type of the value they hold
+	if err := e.Mutate("Held", OperatorSetJSON(`{"Name": "Dave"}`)); err != nil {
+		t.Error("Could not set interface from JSON -", err)
+	}
+	if data.Held != (testEmployee{Name: "Dave"}) {
+		t.Error("Expected Held to be Dave, saw", data.Held)
+	}
+
+	if err := e.Mutate("", OperatorSetJSON(`{"Manager": null, "Held": null}`)); err != nil {
+		t.Error("Could not set root from JSON -", err)
+	}
+	if data.Manager != nil || data.Held != nil {
+		t.Error("Expected fields to be nil, saw", data)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package structeditor
+
+import (
+	"html/template"
+	"net/http"
+	"sync"
+)
+
+// An Option configures an editor created by NewEditor or ServeEditor.
+type Option func(*editor)
+
+// WithLocker makes the editor hold the specified lock while it reads or
+// writes the state. Code elsewhere in the server that touches the state should
+// hold the same lock. If the lock also has RLock and RUnlock methods (as
+// sync.RWMutex does), rendering takes the read lock so views can proceed in
+// parallel. Passing nil disables locking entirely.
+//
+// ViewHandler holds the lock while it writes the page to the client, so a
+// slow client holds up writers until the page is written or ViewHandler's
+// write deadline (30 seconds) passes.
+//
+// By default, an editor guards the state with its own sync.RWMutex, which
+// keeps its own handlers from racing with each other but does not protect
+// against other code accessing the state.
+func WithLocker(lock sync.Locker) Option {
+	return func(e *editor) {
+		e.lock = lock
+	}
+}
+
+// WithReadOnly prevents the editor from mutating the state, even if the state
+// is a pointer. The UI will not offer mutation tools and Mutate will fail.
+func WithReadOnly() Option {
+	return func(e *editor) {
+		e.readOnly = true
+	}
+}
+
+// WithMutatePath sets the path to which requests to change the state are
+// sent, overriding the path passed to NewEditor (or the "url/mutate" path
+// chosen by ServeEditor).
+func WithMutatePath(path string) Option {
+	return func(e *editor) {
+		e.mutateUrl = path
+	}
+}
+
+// WithTitle sets the title of the rendered page.
+func WithTitle(title string) Option {
+	return func(e *editor) {
+		e.title = title
+	}
+}
+
+// WithMaxDepth limits how many levels of nested structs, arrays, slices and
+// maps are rendered; deeper values are elided, and fetched by the UI when
+// they are expanded. Zero means no limit.
+func WithMaxDepth(depth int) Option {
+	return func(e *editor) {
+		e.maxDepth = depth
+	}
+}
+
+// The number of elements of an array or slice rendered at once by default
+const defaultPageSize = 100
+
+// WithPageSize sets how many elements of an array or slice are rendered at
+// once; the UI fetches the others a page at a time. Zero means all elements
+// are rendered. The default is 100.
+func WithPageSize(size int) Option {
+	return func(e *editor) {
+		e.pageSize = size
+	}
+}
+
+// WithBeforeMutate registers a function called before each mutation with the
+// path and operator being applied. If it returns an error, the mutation is
+// cancelled and Mutate returns the error.
+func WithBeforeMutate(hook func(path string, operator Operator) error) Option {
+	return func(e *editor) {
+		e.beforeMutate = hook
+	}
+}
+
+// WithAfterMutate registers a function called after each successful mutation
+// with the path and operator that were applied. The state lock is not held
+// while the function runs.
+func WithAfterMutate(hook func(path string, operator Operator)) Option {
+	return func(e *editor) {
+		e.afterMutate = hook
+	}
+}
+
+// WithHandlerWrapper sets a function used by ServeEditor to wrap each handler
+// it registers, such as an authentication / authorization check.
+func WithHandlerWrapper(wrapper func(http.Handler) http.Handler) Option {
+	return func(e *editor) {
+		e.handlerWrapper = wrapper
+	}
+}
+
+// WithPageTemplate replaces the template for the page wrapping the rendered
+// state. The template is executed with a value providing .Title, .MutateURL
+// and .Content (the rendered state); see STATIC_HEADER for an example.
+func WithPageTemplate(pageTemplate *template.Template) Option {
+	return func(e *editor) {
+		e.pageTemplate = pageTemplate
+	}
+}
+
+// WithUnexportedMutation allows unexported struct fields to be mutated. By
+// default, unexported fields are shown but cannot be changed. Mutation of
+// unexported fields bypasses the Go type system's access restrictions (using
+// package unsafe), so this option is intended for debugging sessions only.
+// Unexported fields can only be mutated if they are addressable (i.e. the
+// state is a pointer).
+func WithUnexportedMutation() Option {
+	return func(e *editor) {
+		e.editUnexported = true
+	}
+}
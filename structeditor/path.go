@@ -16,6 +16,7 @@ package structeditor
 
 import (
 	"fmt"
+	"net/url"
 	"strconv"
 	"strings"
 )
@@ -26,7 +27,8 @@ type Path struct {
 	// name is not ""
 	// index has meaning
 
-	// Name of struct field in path
+	// Name of struct field (or key of map entry) in path. An empty name
+	// (such as an empty map key) has an Index of emptyName instead.
 	Name string
 	// Current variable is array or slice and should be indexed
 	Index int
@@ -65,16 +67,36 @@ func sliceToPath(slice []string) (*Path, error) {
 	return first, nil
 }
 
-// Encodes path part
+// Index of a path part naming the empty string, which would otherwise be
+// indistinguishable from index 0. Written as "%00" in path strings.
+const emptyName = -1
+
+// Returns the path part naming a struct field or map key.
+func namePath(name string) *Path {
+	if name == "" {
+		return &Path{Index: emptyName}
+	}
+	return &Path{Name: name}
+}
+
+// Encodes path part. Parts are unescaped, so map keys containing '.' can be
+// represented. Parts that are plain numbers become indices; anything else
+// (including map keys like "3rd" or "007") becomes a name.
 func encodePath(s string) (*Path, error) {
+	if s == "%00" {
+		return namePath(""), nil
+	}
+	s, err := url.PathUnescape(s)
+	if err != nil {
+		return nil, err
+	}
 	if strings.IndexAny(s, "0123456789") == 0 {
 		result, err := strconv.Atoi(s)
-		if err != nil {
-			return nil, err
+		if err == nil && strconv.Itoa(result) == s {
+			return &Path{
+				Index: result,
+			}, nil
 		}
-		return &Path{
-			Index: result,
-		}, nil
 	}
 
 	return &Path{
@@ -82,6 +104,21 @@ func encodePath(s string) (*Path, error) {
 	}, nil
 }
 
+// Escapes a path part so that it survives a round-trip through StringToPath.
+func escapePathPart(s string) string {
+	s = strings.Replace(s, "%", "%25", -1)
+	return strings.Replace(s, ".", "%2E", -1)
+}
+
+// Returns the text of this path part: the name, or the index formatted as a
+// string. Used to look up map entries, whose keys may be either.
+func (p *Path) part() string {
+	if p.Name != "" || p.Index == emptyName {
+		return p.Name
+	}
+	return strconv.Itoa(p.Index)
+}
+
 // Appends the specified newElement to the path and
 // returns the new root of the path.
 func (p *Path) Append(newElement *Path) *Path {
@@ -127,8 +164,10 @@ func (p *Path) String() string {
 		return ""
 	}
 	subpath := p.Next.String()
-	elt := p.Name
-	if elt == "" {
+	elt := escapePathPart(p.Name)
+	if p.Name == "" && p.Index == emptyName {
+		elt = "%00"
+	} else if elt == "" {
 		elt = fmt.Sprintf("%d", p.Index)
 	}
 	if subpath == "" {
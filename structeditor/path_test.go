@@ -170,3 +170,56 @@ func TestVisitingNilRoot(t *testing.T) {
 
 	assert(p == nil, "After visit, p should be nil but was not.")
 }
+
+func TestPathEscaping(t *testing.T) {
+	data := []string{
+		"plain",
+		"has.dot",
+		"100%",
+		"007",
+		"3rd",
+		"",
+		"%00",
+		"0",
+	}
+
+	for _, key := range data {
+		p := &Path{
+			Name: "map",
+			Next: namePath(key),
+		}
+		parsed, err := StringToPath(p.String())
+		if err != nil {
+			t.Error(key, err)
+			continue
+		}
+		if parsed.Next == nil || parsed.Next.part() != key {
+			t.Error("Expected key", key, "to survive round trip, saw", parsed.Next)
+		}
+	}
+}
+
+func TestEmptyMapKeyPath(t *testing.T) {
+	data := struct {
+		Counts map[string]int
+		List   []int
+	}{map[string]int{"": 1, "0": 2}, []int{3}}
+	e := NewEditor(&data, "")
+
+	p := &Path{Name: "Counts", Next: namePath("")}
+	if p.String() != "Counts.%00" {
+		t.Error("Expected Counts.%00, saw", p.String())
+	}
+	if err := e.Mutate(p.String(), OperatorSet("5")); err != nil {
+		t.Error("Could not set empty key -", err)
+	}
+	if err := e.Mutate("Counts.0", OperatorSet("6")); err != nil {
+		t.Error("Could not set key 0 -", err)
+	}
+	if data.Counts[""] != 5 || data.Counts["0"] != 6 {
+		t.Error("Expected map[:5 0:6], saw", data.Counts)
+	}
+	if err := e.Mutate("List.%00", OperatorSet("4")); err == nil {
+		t.Error("Expected empty name to be refused as a slice index")
+	}
+}
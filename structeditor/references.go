@@ -0,0 +1,126 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package structeditor
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Identifies the value referred to by a pointer, map or slice. The type is
+// included because a pointer to a struct and a pointer to its first field
+// share an address.
+type referenceKey struct {
+	address uintptr
+	t       reflect.Type
+	len     int
+}
+
+// Records the path at which each reference was first seen while walking the
+// state, so that values reachable through more than one path (including
+// through cycles) are only walked once.
+type visitedReferences map[referenceKey]string
+
+// visit records the reference held by v, which was reached at curPath. If
+// the same reference was already visited, returns the path at which it was
+// first seen and true. Values other than non-nil pointers, maps and slices
+// are not references, and are never reported as visited.
+func (visited visitedReferences) visit(v reflect.Value, curPath *Path) (string, bool) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() || (v.Kind() == reflect.Slice && v.Len() == 0) {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	key := referenceKey{v.Pointer(), v.Type(), 0}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	if first, ok := visited[key]; ok {
+		return first, true
+	}
+	visited[key] = curPath.String()
+	return "", false
+}
+
+// Render a reference to a value that has already been rendered at the first
+// path, linking to it
+func renderAlias(v reflect.Value, first string) string {
+	name := first
+	if name == "" {
+		name = "top level"
+	}
+	return fmt.Sprintf("<span class='alias'>(same %s as <a href='#' data-action='reveal' data-path='%s'>%s</a>)</span>",
+		v.Kind(), escape(first), escape(name))
+}
+
+// hasCycle returns true if v refers back to itself, or to a value containing
+// it, through pointers, maps or slices. Values reachable through more than
+// one path are only walked once.
+func hasCycle(v reflect.Value) bool {
+	return findCycle(v, map[referenceKey]bool{}, map[referenceKey]bool{})
+}
+
+// findCycle walks v, recording the references it is inside in enclosing and
+// those already fully walked in walked.
+func findCycle(v reflect.Value, enclosing map[referenceKey]bool, walked map[referenceKey]bool) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() || (v.Kind() == reflect.Slice && v.Len() == 0) {
+			return false
+		}
+		key := referenceKey{v.Pointer(), v.Type(), 0}
+		if v.Kind() == reflect.Slice {
+			key.len = v.Len()
+		}
+		if enclosing[key] {
+			return true
+		}
+		if walked[key] {
+			return false
+		}
+		enclosing[key] = true
+		defer func() {
+			delete(enclosing, key)
+			walked[key] = true
+		}()
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return !v.IsNil() && findCycle(v.Elem(), enclosing, walked)
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if findCycle(v.Index(i), enclosing, walked) {
+				return true
+			}
+		}
+	case reflect.Map:
+		entries := v.MapRange()
+		for entries.Next() {
+			if findCycle(entries.Key(), enclosing, walked) || findCycle(entries.Value(), enclosing, walked) {
+				return true
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if findCycle(v.Field(i), enclosing, walked) {
+				return true
+			}
+		}
+	}
+	return false
+}
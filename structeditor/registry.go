@@ -0,0 +1,255 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package structeditor
+
+import (
+	"fmt"
+	"html/template"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Registries of custom rendering and parsing for specific types, consulted
+// before the built-in handling of every editor, and of the operators
+// available to every editor.
+
+// Describes the value being rendered by a RenderFunc
+type RenderContext struct {
+	// Path to the value, as used in mutation requests
+	Path string
+	// ID of the element the UI reads a new value from when the "change"
+	// button following the rendered HTML is clicked. The element's value
+	// (or, for a checkbox, "true" or "false") is passed to the ParseFunc.
+	InputID string
+	// True if the value can be changed
+	Editable bool
+}
+
+// A RenderFunc returns the HTML for a value of a registered type. The HTML is
+// included in the page as is, so anything taken from the value must be
+// escaped (e.g. with template.HTMLEscapeString).
+type RenderFunc func(v reflect.Value, context RenderContext) (template.HTML, error)
+
+// A ParseFunc returns the value of a registered type described by s, as sent
+// by the UI or passed to OperatorSet. current is the value being replaced.
+type ParseFunc func(s string, current reflect.Value) (reflect.Value, error)
+
+type typeHandler struct {
+	t      reflect.Type
+	render RenderFunc
+	parse  ParseFunc
+}
+
+var typeRegistry struct {
+	sync.RWMutex
+	// Handlers for specific types
+	types map[reflect.Type]*typeHandler
+	// Handlers for types implementing interfaces, in the order registered
+	interfaces []*typeHandler
+}
+
+// RegisterType registers functions used by every editor to render and parse
+// values of type t. If t is an interface type, the functions are used for
+// values of all types implementing it (directly or through a pointer), unless
+// a function was registered for their specific type; if several interfaces
+// match, the first registered is used. Registering a type again replaces its
+// functions.
+//
+// If render is nil, values are rendered as usual, and if parse is nil, values
+// cannot be set.
+func RegisterType(t reflect.Type, render RenderFunc, parse ParseFunc) {
+	typeRegistry.Lock()
+	defer typeRegistry.Unlock()
+	handler := &typeHandler{t, render, parse}
+	if t.Kind() == reflect.Interface {
+		for i, existing := range typeRegistry.interfaces {
+			if existing.t == t {
+				typeRegistry.interfaces[i] = handler
+				return
+			}
+		}
+		typeRegistry.interfaces = append(typeRegistry.interfaces, handler)
+		return
+	}
+	if typeRegistry.types == nil {
+		typeRegistry.types = map[reflect.Type]*typeHandler{}
+	}
+	typeRegistry.types[t] = handler
+}
+
+// registeredHandler returns the functions registered for values of type t,
+// or nil if there are none.
+func registeredHandler(t reflect.Type) *typeHandler {
+	typeRegistry.RLock()
+	defer typeRegistry.RUnlock()
+	if handler, ok := typeRegistry.types[t]; ok {
+		return handler
+	}
+	for _, handler := range typeRegistry.interfaces {
+		if implements(t, handler.t) {
+			return handler
+		}
+	}
+	return nil
+}
+
+// renderRegistered renders a value using the RenderFunc registered for its
+// type. Returns false if there is none, or if the value cannot be read.
+func (r *renderer) renderRegistered(v reflect.Value, curPath *Path) (bool, error) {
+	handler := registeredHandler(v.Type())
+	if handler == nil || handler.render == nil || r.tag.secret {
+		return false, nil
+	}
+	readable, ok := readableValue(v)
+	if !ok {
+		return false, nil
+	}
+	editable := r.editable && handler.parse != nil
+	html, err := handler.render(readable, RenderContext{
+		Path:     curPath.String(),
+		InputID:  inputId(curPath),
+		Editable: editable,
+	})
+	if err != nil {
+		return true, fmt.Errorf("At [%v]: %v", curPath, err)
+	}
+	r.write(string(html))
+	if editable {
+		r.write(actionButton("change", "update", "path", curPath.String()))
+		r.write(actionButton("reset", "reset", "path", curPath.String()))
+	}
+	return true, nil
+}
+
+// setRegistered parses a value using the ParseFunc registered for its type
+// and sets v to it. Returns false if there is none.
+func setRegistered(v reflect.Value, s string) (bool, error) {
+	handler := registeredHandler(v.Type())
+	if handler == nil || handler.parse == nil {
+		return false, nil
+	}
+	current, ok := readableValue(v)
+	if !ok {
+		current = reflect.Zero(v.Type())
+	}
+	newValue, err := handler.parse(s, current)
+	if err != nil {
+		return true, wrapError(ErrInvalidInput, err)
+	}
+	if !newValue.IsValid() || !newValue.Type().AssignableTo(v.Type()) {
+		return true, newError(ErrInvalidInput, "Parsing '%s' did not produce a value of type %v", s, v.Type())
+	}
+	v.Set(newValue)
+	return true, nil
+}
+
+// An OperatorConstructor builds an Operator from the arguments of a mutation
+// request (such as the query parameters sent to MutateHandler).
+type OperatorConstructor func(args url.Values) (Operator, error)
+
+// Describes the button the UI shows for a registered operator
+type OperatorButton struct {
+	// Text of the button
+	Label string
+	// Kinds of values the button is shown next to. Buttons are only shown
+	// next to pointers and interfaces if their kinds are listed explicitly.
+	Kinds []reflect.Kind
+	// Names of arguments the UI asks the user for when the button is clicked,
+	// which are passed to the OperatorConstructor
+	Args []string
+}
+
+type operatorHandler struct {
+	name        string
+	constructor OperatorConstructor
+	button      *OperatorButton
+}
+
+var operatorRegistry struct {
+	sync.RWMutex
+	operators map[string]*operatorHandler
+	// Operators with buttons, in the order registered
+	buttons []*operatorHandler
+}
+
+// RegisterOperator makes an operator available to every editor under the
+// specified name, for use in mutation requests. If button is not nil, the UI
+// shows a button for the operator next to values of the kinds it lists.
+// Registering a name again (including the name of a built-in operator, such
+// as "set") replaces the operator.
+func RegisterOperator(name string, constructor OperatorConstructor, button *OperatorButton) {
+	operatorRegistry.Lock()
+	defer operatorRegistry.Unlock()
+	if operatorRegistry.operators == nil {
+		operatorRegistry.operators = map[string]*operatorHandler{}
+	}
+	if existing, ok := operatorRegistry.operators[name]; ok && existing.button != nil {
+		for i, handler := range operatorRegistry.buttons {
+			if handler == existing {
+				operatorRegistry.buttons = append(operatorRegistry.buttons[:i], operatorRegistry.buttons[i+1:]...)
+				break
+			}
+		}
+	}
+	handler := &operatorHandler{name, constructor, button}
+	operatorRegistry.operators[name] = handler
+	if button != nil {
+		operatorRegistry.buttons = append(operatorRegistry.buttons, handler)
+	}
+}
+
+// registeredOperator returns the constructor for the operator registered
+// under the specified name, or nil if there is none.
+func registeredOperator(name string) OperatorConstructor {
+	operatorRegistry.RLock()
+	defer operatorRegistry.RUnlock()
+	if handler, ok := operatorRegistry.operators[name]; ok {
+		return handler.constructor
+	}
+	return nil
+}
+
+// renderOperatorButtons renders the buttons for registered operators that
+// apply to the kind of a value.
+func (r *renderer) renderOperatorButtons(v reflect.Value, curPath *Path) {
+	if !r.editable {
+		return
+	}
+	operatorRegistry.RLock()
+	defer operatorRegistry.RUnlock()
+	for _, handler := range operatorRegistry.buttons {
+		if !handler.button.appliesTo(v.Kind()) {
+			continue
+		}
+		attributes := []string{"operator", handler.name, "path", curPath.String()}
+		if len(handler.button.Args) > 0 {
+			attributes = append(attributes, "args", strings.Join(handler.button.Args, ","))
+		}
+		r.write(actionButton(handler.button.Label, "operator", attributes...))
+	}
+}
+
+// appliesTo returns true if the button is shown next to values of the
+// specified kind.
+func (b *OperatorButton) appliesTo(kind reflect.Kind) bool {
+	for _, k := range b.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package structeditor
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type rgb struct {
+	R, G, B uint8
+}
+
+type shape interface {
+	Area() float64
+}
+
+type square struct {
+	Side float64
+}
+
+func (s square) Area() float64 {
+	return s.Side * s.Side
+}
+
+type circle struct {
+	Radius float64
+}
+
+func (c *circle) Area() float64 {
+	return 3 * c.Radius * c.Radius
+}
+
+func init() {
+	RegisterType(reflect.TypeOf(rgb{}),
+		func(v reflect.Value, context RenderContext) (template.HTML, error) {
+			c := v.Interface().(rgb)
+			return template.HTML(fmt.Sprintf("<input type='color' id='%s' value='#%02x%02x%02x'>",
+				template.HTMLEscapeString(context.InputID), c.R, c.G, c.B)), nil
+		},
+		func(s string, current reflect.Value) (reflect.Value, error) {
+			var c rgb
+			_, err := fmt.Sscanf(s, "#%02x%02x%02x", &c.R, &c.G, &c.B)
+			return reflect.ValueOf(c), err
+		})
+	RegisterType(reflect.TypeOf((*shape)(nil)).Elem(),
+		func(v reflect.Value, context RenderContext) (template.HTML, error) {
+			area := v.Addr().Interface().(shape).Area()
+			return template.HTML(fmt.Sprintf("<span>area %v</span>", area)), nil
+		},
+		nil)
+	// Registered for a specific type, so preferred over the interface
+	RegisterType(reflect.TypeOf(square{}),
+		nil,
+		func(s string, current reflect.Value) (reflect.Value, error) {
+			return reflect.ValueOf(s), nil
+		})
+}
+
+type registeredStruct struct {
+	Color  rgb
+	Circle circle
+	Square square
+}
+
+func TestRenderRegistered(t *testing.T) {
+	testCase := &registeredStruct{
+		Color:  rgb{255, 0, 16},
+		Circle: circle{2},
+		Square: square{3},
+	}
+	e := editor{state: testCase}
+	result, err := e.unwrappedRender()
+	if err != nil {
+		t.Error("Rendering error:", err)
+	}
+	expected := "&" + divString("") + "registeredStruct {<ul><li>Color: " +
+		"<input type='color' id='input-Color' value='#ff0010'>" + editButtons("Color") +
+		",</li><li>Circle: <span>area 12</span>" +
+		",</li><li>Square: " + divString("Square") + "square {<ul><li>Side: " +
+		"<input type='number' id='input-Square.Side' value='3' step='any'>" + editButtons("Square.Side") +
+		",</li>}" + resetButton("Square") + jsonPanel("Square") + "</ul></div>,</li>}" + resetButton("") + jsonPanel("") + "</ul></div>"
+	if result != expected {
+		t.Error("Expected", expected, "saw", result)
+	}
+}
+
+func TestModifyRegistered(t *testing.T) {
+	data := registeredStruct{}
+	e := NewEditor(&data, "")
+
+	err := e.Mutate("Color", OperatorSet("#0a0b0c"))
+	if err != nil {
+		t.Error("Color -", err)
+	}
+	if data.Color != (rgb{10, 11, 12}) {
+		t.Error("Expected", rgb{10, 11, 12}, "saw", data.Color)
+	}
+
+	refused := []struct {
+		path     string
+		newValue string
+		kind     error
+	}{
+		{"Color", "red", ErrInvalidInput},
+		// Parses to a string rather than a square
+		{"Square", "big", ErrInvalidInput},
+		// Registered without a ParseFunc, so falls back to the kind
+		{"Circle", "1", ErrInvalidInput},
+	}
+	for _, mutation := range refused {
+		err := e.Mutate(mutation.path, OperatorSet(mutation.newValue))
+		if !errors.Is(err, mutation.kind) {
+			t.Error(mutation.path, "- expected", mutation.kind, "saw", err)
+		}
+	}
+}
+
+// Multiplies a complex number by a factor
+type operatorScale struct {
+	factor complex128
+}
+
+func (o *operatorScale) ModifiesPointer() bool {
+	return false
+}
+
+func (o *operatorScale) Do(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Complex64, reflect.Complex128:
+		v.SetComplex(v.Complex() * o.factor)
+		return nil
+	}
+	return newError(ErrInvalidInput, "Unable to scale type %v", v.Kind())
+}
+
+func init() {
+	RegisterOperator("scale", func(args url.Values) (Operator, error) {
+		factor, err := strconv.ParseComplex(args.Get("factor"), 128)
+		if err != nil {
+			return nil, err
+		}
+		return &operatorScale{factor}, nil
+	}, &OperatorButton{
+		Label: "scale",
+		Kinds: []reflect.Kind{reflect.Complex64, reflect.Complex128},
+		Args:  []string{"factor"},
+	})
+}
+
+func TestRegisteredOperator(t *testing.T) {
+	data := &complexHolder{Small: complex(1, 1)}
+	e := NewEditor(data, "")
+
+	operator, err := e.OperatorFor(url.Values{"operator": {"scale"}, "factor": {"2i"}})
+	if err != nil {
+		t.Fatal("Unable to build scale operator -", err)
+	}
+	if err = e.Mutate("Small", operator); err != nil {
+		t.Error("Small -", err)
+	}
+	if data.Small != complex(-2, 2) {
+		t.Error("Expected", complex(-2, 2), "saw", data.Small)
+	}
+
+	_, err = e.OperatorFor(url.Values{"operator": {"scale"}, "factor": {"lots"}})
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Error("Expected invalid input for bad argument, saw", err)
+	}
+	_, err = e.OperatorFor(url.Values{"operator": {"explode"}})
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Error("Expected invalid input for unknown operator, saw", err)
+	}
+
+	result, err := e.(*editor).unwrappedRender()
+	if err != nil {
+		t.Error("Rendering error:", err)
+	}
+	button := "<button data-action='operator' data-operator='scale' data-path='Small' data-args='factor'>scale</button>"
+	if !strings.Contains(result, inputString("(-2+2i)", "Small")+editButtons("Small")+button) {
+		t.Error("Expected scale button after Small, saw", result)
+	}
+}
@@ -16,78 +16,227 @@ package structeditor
 
 import (
 	"fmt"
+	"html/template"
+	"io"
+	"math"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Contains state used as a render is being evaluated
 type renderer struct {
-	nextId   int
+	// Destination of the rendered HTML, and the first error writing to it
+	out      io.Writer
+	writeErr error
 	editable bool
+	// Current and maximum depth of nested composite values; a maxDepth of 0
+	// means there is no limit.
+	depth    int
+	maxDepth int
+	// If true, unexported struct fields are editable (see
+	// WithUnexportedMutation)
+	editUnexported bool
+	// Number of elements of an array or slice to render at once; 0 means
+	// all elements are rendered.
+	pageSize int
+	// If offsetPath is set, the array or slice at that path is rendered
+	// starting at element offset rather than at the first element.
+	offsetPath *string
+	offset     int
+	// Struct tag settings for the field being rendered
+	tag fieldTag
+	// Pointers, maps and slices already rendered, which are rendered as
+	// links to their first occurrence if seen again
+	visited visitedReferences
 }
 
 // Render the state into HTML for serving
 func (e *editor) Render() (string, error) {
-	result, err := e.unwrappedRender()
+	var result strings.Builder
+	err := e.RenderTo(&result)
 	if err != nil {
 		return "", err
 	}
-	return wrapContent(result, e.mutateUrl), nil
+	return result.String(), nil
+}
+
+// RenderTo writes the state to w as HTML for serving. The HTML is written as
+// the state is walked, so the read lock on the state is held until writing
+// is complete.
+func (e *editor) RenderTo(w io.Writer) error {
+	header, footer, err := e.pageParts()
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, header)
+	if err != nil {
+		return err
+	}
+	err = e.renderState(w)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, footer)
+	return err
 }
 
 func (e *editor) unwrappedRender() (string, error) {
-	r := renderer{}
-	v := reflect.ValueOf(e.state)
-	if v.Kind() == reflect.Ptr {
-		r.editable = true
+	var result strings.Builder
+	err := e.renderState(&result)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// renderState writes the state to w as HTML, without the surrounding page.
+func (e *editor) renderState(w io.Writer) error {
+	defer e.lockForRead()()
+	r := e.newRenderer(w)
+	return r.render(reflect.ValueOf(e.state), nil)
+}
+
+// renderFragment writes only the value at the specified path to w, without
+// the surrounding page. If the value is a long array or slice, the page of
+// its elements starting at offset is rendered. The UI uses fragments to
+// refresh part of the page after a mutation, to show parts of the state too
+// deep to render up front and to move between pages of long arrays and
+// slices.
+func (e *editor) renderFragment(w io.Writer, path string, offset int) error {
+	p, err := StringToPath(path)
+	if err != nil {
+		return wrapError(ErrInvalidInput, err)
+	}
+	defer e.lockForRead()()
+	found, err := e.findValueToChange(p, reflect.ValueOf(e.state), false, fieldTag{})
+	if err != nil {
+		return err
+	}
+	// The UI replaces the element for the composite value at the path, so
+	// skip the pointers and interfaces leading to it.
+	v := found.value
+	for (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	r := e.newRenderer(w)
+	r.editable = r.editable && !found.tag.readOnly
+	r.tag = found.tag
+	r.offsetPath = &path
+	r.offset = offset
+	return r.render(v, p)
+}
+
+func (e *editor) newRenderer(w io.Writer) *renderer {
+	return &renderer{
+		out:            w,
+		editable:       reflect.ValueOf(e.state).Kind() == reflect.Ptr && !e.readOnly,
+		maxDepth:       e.maxDepth,
+		pageSize:       e.pageSize,
+		editUnexported: e.editUnexported,
+		visited:        visitedReferences{},
+	}
+}
+
+// Render a value and everything it contains, returning the first error
+// either rendering or writing the HTML
+func (r *renderer) render(v reflect.Value, curPath *Path) error {
+	err := r.renderElement(v, curPath)
+	if err != nil {
+		return err
+	}
+	return r.writeErr
+}
+
+// write writes HTML to the renderer's output. After an error writing, the
+// error is kept and further output is discarded.
+func (r *renderer) write(html string) {
+	if r.writeErr == nil {
+		_, r.writeErr = io.WriteString(r.out, html)
+	}
+}
+
+// printf formats HTML and writes it to the renderer's output, as with write.
+func (r *renderer) printf(format string, args ...interface{}) {
+	if r.writeErr == nil {
+		_, r.writeErr = fmt.Fprintf(r.out, format, args...)
 	}
-	return r.renderElement(reflect.ValueOf(e.state), nil)
 }
 
 // Render an unknown element
-func (r *renderer) renderElement(v reflect.Value, curPath *Path) (string, error) {
+func (r *renderer) renderElement(v reflect.Value, curPath *Path) error {
+	if r.writeErr != nil {
+		// Nothing more can be written, so stop walking the state.
+		return r.writeErr
+	}
+	var err error
+	if rendered, registeredErr := r.renderRegistered(v, curPath); rendered {
+		err = registeredErr
+	} else if text, ok := formatScalar(v); ok {
+		err = r.renderEditField(v, text, curPath)
+	} else {
+		err = r.renderComposite(v, curPath)
+	}
+	if err != nil {
+		return err
+	}
+	r.renderOperatorButtons(v, curPath)
+	return nil
+}
+
+// formatScalar returns the string form of a scalar (number, bool, string,
+// well-known type or encoding.TextMarshaler) value, which is also the form
+// accepted by OperatorSet. Returns false if
+// the value is not a scalar.
+func formatScalar(v reflect.Value) (string, bool) {
+	if text, ok := formatWellKnown(v); ok {
+		return text, true
+	}
+	if text, ok := formatText(v); ok {
+		return text, true
+	}
 	switch v.Kind() {
-	case reflect.Uint8:
-		return r.renderEditField(fmt.Sprintf("%d", v.Uint()),
-			curPath)
-	case reflect.Uint16:
-		return r.renderEditField(fmt.Sprintf("%d", v.Uint()),
-			curPath)
-	case reflect.Uint32:
-		return r.renderEditField(fmt.Sprintf("%d", v.Uint()),
-			curPath)
-	case reflect.Uint64:
-		return r.renderEditField(fmt.Sprintf("%d", v.Uint()), curPath)
-	case reflect.Uint:
-		return r.renderEditField(fmt.Sprintf("%d", v.Uint()), curPath)
-	case reflect.Int8:
-		return r.renderEditField(fmt.Sprintf("%d", v.Int()), curPath)
-	case reflect.Int16:
-		return r.renderEditField(fmt.Sprintf("%d", v.Int()), curPath)
-	case reflect.Int32:
-		return r.renderEditField(fmt.Sprintf("%d", v.Int()), curPath)
-	case reflect.Int64:
-		return r.renderEditField(fmt.Sprintf("%d", v.Int()), curPath)
-	case reflect.Int:
-		return r.renderEditField(fmt.Sprintf("%d", v.Int()), curPath)
-	case reflect.Float32:
-		return r.renderEditField(fmt.Sprintf("%f", v.Float()), curPath)
-	case reflect.Float64:
-		return r.renderEditField(fmt.Sprintf("%f", v.Float()), curPath)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64:
+		return fmt.Sprintf("%d", v.Uint()), true
+	case reflect.Int, reflect.Int8, reflect.Int16,
+		reflect.Int32, reflect.Int64:
+		return fmt.Sprintf("%d", v.Int()), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), true
+	case reflect.Complex64, reflect.Complex128:
+		return strconv.FormatComplex(v.Complex(), 'g', -1, v.Type().Bits()), true
 	case reflect.Bool:
-		return r.renderEditField(fmt.Sprintf("%v", v.Bool()), curPath)
+		return fmt.Sprintf("%v", v.Bool()), true
 	case reflect.String:
-		return r.renderEditField(v.String(), curPath)
-	default:
-		return r.renderComposite(v, curPath)
-		// Next: render complex type function, struct
-		// render function
-
+		return v.String(), true
 	}
+	return "", false
 }
 
 // Render a composite element type (any type containing another type): struct,
 // array, slice, map, &c
-func (r *renderer) renderComposite(elt reflect.Value, curPath *Path) (string, error) {
+func (r *renderer) renderComposite(elt reflect.Value, curPath *Path) error {
+	switch elt.Kind() {
+	case reflect.Struct, reflect.Array, reflect.Slice, reflect.Map:
+		if r.maxDepth > 0 && r.depth >= r.maxDepth {
+			r.printf("<div data-path='%s'>%s {%s}</div>",
+				escape(curPath.String()), escape(elt.Type().String()),
+				actionButton("...", "expand", "path", curPath.String()))
+			return nil
+		}
+		r.depth++
+		defer func() { r.depth-- }()
+	}
+	switch elt.Kind() {
+	case reflect.Slice, reflect.Map:
+		if first, seen := r.visited.visit(elt, curPath); seen {
+			r.write(renderAlias(elt, first))
+			return nil
+		}
+	}
 	switch elt.Kind() {
 	case reflect.Struct:
 		return r.renderStruct(elt, curPath)
@@ -95,112 +244,460 @@ func (r *renderer) renderComposite(elt reflect.Value, curPath *Path) (string, er
 		return r.renderArray(elt, curPath)
 	case reflect.Slice:
 		return r.renderSlice(elt, curPath)
+	case reflect.Map:
+		return r.renderMap(elt, curPath)
 	case reflect.Ptr:
 		return r.renderPtr(elt, curPath)
+	case reflect.Interface:
+		return r.renderInterface(elt, curPath)
 	default:
-		return "", fmt.Errorf("At [%v]: Unknown composite render type: %v", curPath, elt.Kind())
+		return fmt.Errorf("At [%v]: Unknown composite render type: %v", curPath, elt.Kind())
 	}
 }
 
 // Render a struct
-func (r *renderer) renderStruct(v reflect.Value, curPath *Path) (string, error) {
+func (r *renderer) renderStruct(v reflect.Value, curPath *Path) error {
 	t := v.Type()
 
-	result := fmt.Sprintf("<div>%s {<ul>", t.Name())
+	r.printf("<div data-path='%s'>%s ", escape(curPath.String()), escape(t.Name()))
+	if description, ok := describe(v); ok && !r.tag.secret {
+		r.printf("<span class='description'>%s</span> ", escape(description))
+	}
+	r.write("{<ul>")
 	for i := 0; i < t.NumField(); i++ {
 		sf := t.Field(i)
-		var rendered string
+		sfTag := parseFieldTag(sf)
+		if sfTag.hidden {
+			continue
+		}
+		label := sf.Name
+		if sfTag.label != "" {
+			label = sfTag.label
+		}
 		var err error
+		exported := sf.PkgPath == ""
 		curPath.Visiting(&Path{
 			Name: sf.Name,
 		}, func(updatedPath *Path) {
-			result += fmt.Sprintf("<li>%s: ", sf.Name)
+			if exported {
+				r.printf("<li>%s: ", escape(label))
+			} else {
+				r.printf("<li class='unexported'>%s: ", escape(label))
+			}
 			subvalue := v.Field(i)
-			rendered, err = r.renderElement(
+			wasEditable, wasTag := r.editable, r.tag
+			r.tag = r.tag.enclosing(sfTag)
+			if (!exported && !r.editUnexported) || r.tag.readOnly {
+				r.editable = false
+			}
+			err = r.renderElement(
 				subvalue, updatedPath)
+			r.editable, r.tag = wasEditable, wasTag
 		})
 		if err != nil {
-			return "", err
+			return err
 		}
-		result += fmt.Sprintf("%s,</li>", rendered)
+		r.write(",</li>")
+	}
+	r.write("}")
+	if r.editable {
+		r.write(actionButton("reset", "reset", "path", curPath.String()))
+		r.write(renderJSONPanel(curPath))
 	}
-	result += "}</ul></div>"
-	return result, nil
+	r.write("</ul></div>")
+	return nil
 }
 
-func (r *renderer) renderArray(v reflect.Value, curPath *Path) (string, error) {
-	t := v.Type()
-	innerType := t.Elem()
-	len := v.Len()
-	result := fmt.Sprintf("<div>[%d]%s {<ul>", len, innerType)
-	for i := 0; i < len; i++ {
+func (r *renderer) renderArray(v reflect.Value, curPath *Path) error {
+	innerType := v.Type().Elem()
+	start, end := r.pageBounds(v.Len(), curPath)
+	r.write(r.openList(curPath, v.Len(), start))
+	r.printf("[%d]%s {<ul>", v.Len(), escape(innerType.String()))
+	err := r.renderItems(v, curPath, start, end)
+	if err != nil {
+		return err
+	}
+	r.write("}" + r.renderPageButtons(curPath, v.Len(), start, end))
+	if r.editable {
+		r.write(actionButton("reset", "reset", "path", curPath.String()))
+		r.write(renderJSONPanel(curPath))
+	}
+	r.write("</ul></div>")
+	return nil
+}
+
+func (r *renderer) renderSlice(v reflect.Value, curPath *Path) error {
+	innerType := v.Type().Elem()
+	start, end := r.pageBounds(v.Len(), curPath)
+	r.write(r.openList(curPath, v.Len(), start))
+	r.printf("[]%s {<ul>", escape(innerType.String()))
+	err := r.renderItems(v, curPath, start, end)
+	if err != nil {
+		return err
+	}
+	r.write("}" + r.renderPageButtons(curPath, v.Len(), start, end))
+	if r.editable {
+		r.write(actionButton("+", "grow", "path", curPath.String()))
+		r.write(actionButton("-", "shrink", "path", curPath.String()))
+		r.write(actionButton("resize", "resize", "path", curPath.String()))
+		r.write(actionButton("clear", "clear", "path", curPath.String()))
+		r.write(actionButton("reset", "reset", "path", curPath.String()))
+		r.write(renderJSONPanel(curPath))
+	}
+	r.write("</ul></div>")
+	return nil
+}
+
+// Render a panel showing the composite value at curPath as JSON, which can be
+// edited (or replaced with a pasted document) and applied to the value. The
+// UI loads the JSON when the panel is opened.
+func renderJSONPanel(curPath *Path) string {
+	path := escape(curPath.String())
+	return fmt.Sprintf("<details class='json' data-path='%s'><summary>JSON</summary><textarea id='json-%s'></textarea>", path, path) +
+		actionButton("apply", "setJSON", "path", curPath.String()) + "</details>"
+}
+
+// pageBounds returns the range of elements of an array or slice of the
+// specified length to render. Long arrays and slices are rendered a page at a
+// time, starting at the beginning unless the renderer was asked for a
+// particular offset into the one at curPath.
+func (r *renderer) pageBounds(length int, curPath *Path) (int, int) {
+	if r.pageSize <= 0 || length <= r.pageSize {
+		return 0, length
+	}
+	start := 0
+	if r.offsetPath != nil && *r.offsetPath == curPath.String() {
+		start = r.offset
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start >= length {
+		start = (length - 1) / r.pageSize * r.pageSize
+	}
+	end := start + r.pageSize
+	if end > length {
+		end = length
+	}
+	return start, end
+}
+
+// Open the container for an array or slice. If the array or slice is split
+// into pages, the container records the offset of the page shown, so that
+// the UI can fetch the same page again when refreshing it.
+func (r *renderer) openList(curPath *Path, length int, start int) string {
+	if r.pageSize <= 0 || length <= r.pageSize {
+		return fmt.Sprintf("<div data-path='%s'>", escape(curPath.String()))
+	}
+	return fmt.Sprintf("<div data-path='%s' data-offset='%d'>", escape(curPath.String()), start)
+}
+
+// Render the elements of an array or slice from start to end as list items
+func (r *renderer) renderItems(v reflect.Value, curPath *Path, start int, end int) error {
+	for i := start; i < end; i++ {
 		subelem := v.Index(i)
-		var subtext string
 		var err error
+		r.write("<li>")
+		if r.editable {
+			r.printf("<span class='handle' draggable='true' data-path='%s' data-index='%d' title='Drag to move'>&#8801;</span>",
+				escape(curPath.String()), i)
+		}
 		curPath.Visiting(&Path{
 			Index: i,
 		}, func(updatedPath *Path) {
-			subtext, err = r.renderElement(subelem, updatedPath)
+			err = r.renderElement(subelem, updatedPath)
 		})
 		if err != nil {
-			return "", err
+			return err
 		}
-		result += fmt.Sprintf("<li>%s,</li>", subtext)
+		if r.editable {
+			r.write(r.renderItemButtons(curPath, i, v.Len(), v.Kind() == reflect.Slice))
+		}
+		r.write(",</li>")
 	}
-	result += "}</ul></div>"
-	return result, nil
+	return nil
 }
 
-func (r *renderer) renderSlice(v reflect.Value, curPath *Path) (string, error) {
+// Render the buttons next to an element of an array or slice, to move it
+// and, if the length can change, to insert an element before it, delete it or
+// duplicate it
+func (r *renderer) renderItemButtons(curPath *Path, index int, length int, resizable bool) string {
+	path := curPath.String()
+	indexText := strconv.Itoa(index)
+	result := ""
+	if resizable {
+		result += actionButton("insert", "insertItem", "path", path, "index", indexText)
+		result += actionButton("delete", "deleteItem", "path", path, "index", indexText)
+		result += actionButton("duplicate", "duplicateItem", "path", path, "index", indexText)
+	}
+	if index > 0 {
+		result += actionButton("up", "swapItems", "path", path,
+			"from", indexText, "to", strconv.Itoa(index-1))
+	}
+	if index < length-1 {
+		result += actionButton("down", "swapItems", "path", path,
+			"from", indexText, "to", strconv.Itoa(index+1))
+	}
+	result += actionButton("move", "moveItem", "path", path, "from", indexText)
+	return result
+}
+
+// Render the position of the page shown of a long array or slice, with
+// buttons to show the previous and next pages
+func (r *renderer) renderPageButtons(curPath *Path, length int, start int, end int) string {
+	if start == 0 && end == length {
+		return ""
+	}
+	result := ""
+	if start > 0 {
+		previous := start - r.pageSize
+		if previous < 0 {
+			previous = 0
+		}
+		result += actionButton("<", "page", "path", curPath.String(), "offset", strconv.Itoa(previous))
+	}
+	result += fmt.Sprintf("<span class='page'>%d-%d of %d</span>", start, end-1, length)
+	if end < length {
+		result += actionButton(">", "page", "path", curPath.String(), "offset", strconv.Itoa(end))
+	}
+	return result
+}
+
+func (r *renderer) renderMap(v reflect.Value, curPath *Path) error {
 	t := v.Type()
-	innerType := t.Elem()
-	len := v.Len()
-	result := fmt.Sprintf("<div>[]%s {<ul>", innerType)
-	for i := 0; i < len; i++ {
-		subelem := v.Index(i)
-		var subtext string
+	r.printf("<div data-path='%s'>map[%s]%s {<ul>",
+		escape(curPath.String()), escape(t.Key().String()), escape(t.Elem().String()))
+	var entries []mapEntry
+	if r.tag.secret {
+		// The keys of a secret map may be as sensitive as its values, so
+		// show neither
+		r.printf("<li class='secret'>secret (length %d)</li>", v.Len())
+	} else {
+		entries = sortedMapEntries(v)
+	}
+	for _, entry := range entries {
+		keyText := formatMapKey(entry.key)
+		subelem := entry.value
 		var err error
-		curPath.Visiting(&Path{
-			Index: i,
-		}, func(updatedPath *Path) {
-			subtext, err = r.renderElement(subelem, updatedPath)
+		r.printf("<li>%s: ", escape(keyText))
+		curPath.Visiting(namePath(keyText), func(updatedPath *Path) {
+			err = r.renderElement(subelem, updatedPath)
 		})
 		if err != nil {
-			return "", err
+			return err
+		}
+		if r.editable {
+			r.write(actionButton("-", "deleteEntry", "path", curPath.String(), "key", keyText))
 		}
-		result += fmt.Sprintf("<li>%s,</li>", subtext)
+		r.write(",</li>")
 	}
-	result += "}"
+	r.write("}")
 	if r.editable {
-		result += fmt.Sprintf("<button onclick=\"grow('%s')\">+</button>",
-			curPath.String())
-		result += fmt.Sprintf("<button onclick=\"shrink('%s')\">-</button>",
-			curPath.String())
+		r.printf("<input type='text' id='key-%s' placeholder='key'>",
+			escape(curPath.String()))
+		r.write(actionButton("+", "insertEntry", "path", curPath.String()))
+		r.write(actionButton("clear", "clear", "path", curPath.String()))
+		r.write(actionButton("reset", "reset", "path", curPath.String()))
+		r.write(renderJSONPanel(curPath))
 	}
-	result += "</ul></div>"
-	return result, nil
+	r.write("</ul></div>")
+	return nil
 }
 
-func (r *renderer) renderPtr(v reflect.Value, curPath *Path) (string, error) {
+// An entry of a map
+type mapEntry struct {
+	key   reflect.Value
+	value reflect.Value
+}
+
+// sortedMapEntries returns the entries of a map in a stable order: by key,
+// numerically for numeric keys (with NaN first) and by their string form
+// otherwise. Entries are read together with their keys, since looking a
+// value up again by key fails for keys not equal to themselves (NaN).
+func sortedMapEntries(v reflect.Value) []mapEntry {
+	var entries []mapEntry
+	for iter := v.MapRange(); iter.Next(); {
+		entries = append(entries, mapEntry{iter.Key(), iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i].key, entries[j].key
+		switch a.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16,
+			reflect.Int32, reflect.Int64:
+			return a.Int() < b.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16,
+			reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return a.Uint() < b.Uint()
+		case reflect.Float32, reflect.Float64:
+			return a.Float() < b.Float() || (math.IsNaN(a.Float()) && !math.IsNaN(b.Float()))
+		}
+		return formatMapKey(a) < formatMapKey(b)
+	})
+	return entries
+}
+
+// formatMapKey returns the string form of a map key, which is also the form
+// used to address the entry in a Path.
+func formatMapKey(key reflect.Value) string {
+	if text, ok := formatWellKnown(key); ok {
+		return text
+	}
+	if text, ok := formatText(key); ok {
+		return text
+	}
+	switch key.Kind() {
+	case reflect.String:
+		return key.String()
+	case reflect.Int, reflect.Int8, reflect.Int16,
+		reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(key.Float(), 'g', -1, 64)
+	case reflect.Bool:
+		return strconv.FormatBool(key.Bool())
+	}
+	return fmt.Sprint(key)
+}
+
+// Render a pointer. Editable pointers (other than the state itself, which
+// the editor cannot replace) get buttons to clear them or allocate a new
+// value for them to point to.
+func (r *renderer) renderPtr(v reflect.Value, curPath *Path) error {
+	editable := r.editable && curPath != nil
 	if v.IsNil() {
-		return "nil", nil
+		r.write("nil")
+		if editable {
+			r.write(actionButton("new", "allocate", "path", curPath.String()))
+			r.write(renderJSONPanel(curPath))
+		}
+		return nil
+	}
+	r.write("&")
+	if first, seen := r.visited.visit(v, curPath); seen {
+		r.write(renderAlias(v, first))
+	} else {
+		err := r.renderElement(v.Elem(), curPath)
+		if err != nil {
+			return err
+		}
+	}
+	if editable {
+		r.write(actionButton("nil", "clearPointer", "path", curPath.String()))
+	}
+	return nil
+}
+
+// Render an interface, labeled with the type of the value it holds (or with
+// its own type, if it is nil)
+func (r *renderer) renderInterface(v reflect.Value, curPath *Path) error {
+	if v.IsNil() {
+		r.printf("(%s) nil", escape(v.Type().String()))
+		return nil
 	}
 	innerValue := v.Elem()
-	innerText, err := r.renderElement(innerValue, curPath)
-	return fmt.Sprintf("&%s", innerText), err
+	r.printf("(%s) ", escape(innerValue.Type().String()))
+	return r.renderElement(innerValue, curPath)
 }
 
-func (r *renderer) getNextId() string {
-	id := r.nextId
-	r.nextId += 1
-	return fmt.Sprintf("input-%d", id)
+// escape escapes text for inclusion in HTML, either as element content or as
+// a quoted attribute value.
+func escape(s string) string {
+	return template.HTMLEscapeString(s)
 }
 
-func (r *renderer) renderEditField(value string, curPath *Path) (string, error) {
-	nextId := r.getNextId()
-	result := fmt.Sprintf("<input type='text' id='%s' value='%s'>", nextId, value)
-	if r.editable {
-		result += fmt.Sprintf("<button onclick=\"update('%s', '%s')\">change</button>", curPath.String(), nextId)
+// actionButton renders a button that runs the named action in the UI's
+// script when clicked. The attributes (alternating names and values) are
+// passed to the action as data attributes.
+func actionButton(text string, action string, attributes ...string) string {
+	result := fmt.Sprintf("<button data-action='%s'", escape(action))
+	for i := 0; i+1 < len(attributes); i += 2 {
+		result += fmt.Sprintf(" data-%s='%s'", escape(attributes[i]), escape(attributes[i+1]))
+	}
+	return result + fmt.Sprintf(">%s</button>", escape(text))
+}
+
+// inputId returns the ID of the input field for the scalar value at a path.
+// IDs are derived from paths so that they are stable when part of the page is
+// refreshed.
+func inputId(curPath *Path) string {
+	return "input-" + curPath.String()
+}
+
+// Render the input field for a scalar, choosing a widget appropriate to its
+// type
+func (r *renderer) renderEditField(v reflect.Value, value string, curPath *Path) error {
+	nextId := escape(inputId(curPath))
+	tag := r.tag
+	tag.enum = allowedValues(v, tag)
+	switch {
+	case tag.secret:
+		r.printf("<input type='password' id='%s' placeholder='secret'>", nextId)
+	case len(tag.enum) > 0:
+		r.printf("<select id='%s'>", nextId)
+		if !tag.allows(value) {
+			r.printf("<option selected>%s</option>", escape(value))
+		}
+		for _, allowed := range tag.enum {
+			if allowed == value {
+				r.printf("<option selected>%s</option>", escape(allowed))
+			} else {
+				r.printf("<option>%s</option>", escape(allowed))
+			}
+		}
+		r.write("</select>")
+	case v.Type() == timeType:
+		current, _ := interfaceOf(v)
+		t, _ := current.(time.Time)
+		r.printf("<input type='datetime-local' id='%s' value='%s' step='1'>",
+			nextId, t.Format(datetimeLocalLayout))
+	default:
+		r.write(renderInput(v, nextId, value))
+	}
+	if r.editable && !marshalsTextOnly(v.Type()) {
+		r.write(actionButton("change", "update", "path", curPath.String()))
+		r.write(actionButton("reset", "reset", "path", curPath.String()))
+	}
+	if description, ok := describe(v); ok && !r.tag.secret {
+		r.printf("<span class='description'>%s</span>", escape(description))
+	}
+	return nil
+}
+
+// Render an input for a scalar according to its kind. The id must already be
+// escaped.
+func renderInput(v reflect.Value, id string, value string) string {
+	value = escape(value)
+	if _, ok := wellKnownTypes[v.Type()]; ok || implements(v.Type(), textMarshalerType) {
+		return fmt.Sprintf("<input type='text' id='%s' value='%s'>", id, value)
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return fmt.Sprintf("<input type='checkbox' id='%s' checked>", id)
+		}
+		return fmt.Sprintf("<input type='checkbox' id='%s'>", id)
+	case reflect.Int, reflect.Int8, reflect.Int16,
+		reflect.Int32, reflect.Int64:
+		bits := uint(v.Type().Bits())
+		min := int64(-1) << (bits - 1)
+		max := -(min + 1)
+		return fmt.Sprintf("<input type='number' id='%s' value='%s' min='%d' max='%d' step='1'>",
+			id, value, min, max)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64:
+		bits := uint(v.Type().Bits())
+		max := ^uint64(0) >> (64 - bits)
+		return fmt.Sprintf("<input type='number' id='%s' value='%s' min='0' max='%d' step='1'>",
+			id, value, max)
+	case reflect.Float32, reflect.Float64:
+		return fmt.Sprintf("<input type='number' id='%s' value='%s' step='any'>", id, value)
+	case reflect.String:
+		if strings.Contains(value, "\n") {
+			return fmt.Sprintf("<textarea id='%s'>%s</textarea>", id, value)
+		}
 	}
-	return result, nil
+	return fmt.Sprintf("<input type='text' id='%s' value='%s'>", id, value)
 }
@@ -15,8 +15,16 @@
 package structeditor
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"net"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 )
 
 type exampleStruct struct {
@@ -25,17 +33,82 @@ type exampleStruct struct {
 	myBool   bool
 }
 
-func inputString(value string, index int) string {
-	return fmt.Sprintf("<input type='text' id='input-%d' value='%s'>", index, value)
+func inputString(value string, path string) string {
+	return fmt.Sprintf("<input type='text' id='input-%s' value='%s'>", path, value)
 
 }
 
+func divString(path string) string {
+	return fmt.Sprintf("<div data-path='%s'>", path)
+}
+
 func sliceEditButtons(path string) string {
-	return fmt.Sprintf("<button onclick=\"grow('%s')\">+</button><button onclick=\"shrink('%s')\">-</button>", path, path)
+	return fmt.Sprintf("<button data-action='grow' data-path='%s'>+</button><button data-action='shrink' data-path='%s'>-</button>", path, path) +
+		fmt.Sprintf("<button data-action='resize' data-path='%s'>resize</button><button data-action='clear' data-path='%s'>clear</button>", path, path) +
+		resetButton(path) + jsonPanel(path)
+}
+
+func jsonPanel(path string) string {
+	return fmt.Sprintf("<details class='json' data-path='%s'><summary>JSON</summary><textarea id='json-%s'></textarea>", path, path) +
+		fmt.Sprintf("<button data-action='setJSON' data-path='%s'>apply</button></details>", path)
+}
+
+func resetButton(path string) string {
+	return fmt.Sprintf("<button data-action='reset' data-path='%s'>reset</button>", path)
+}
+
+func itemHandle(path string, index int) string {
+	return fmt.Sprintf("<span class='handle' draggable='true' data-path='%s' data-index='%d' title='Drag to move'>&#8801;</span>", path, index)
+}
+
+func sliceItemButtons(path string, index int, length int) string {
+	return fmt.Sprintf("<button data-action='insertItem' data-path='%s' data-index='%d'>insert</button>", path, index) +
+		fmt.Sprintf("<button data-action='deleteItem' data-path='%s' data-index='%d'>delete</button>", path, index) +
+		fmt.Sprintf("<button data-action='duplicateItem' data-path='%s' data-index='%d'>duplicate</button>", path, index) +
+		arrayItemButtons(path, index, length)
+}
+
+func arrayItemButtons(path string, index int, length int) string {
+	result := ""
+	if index > 0 {
+		result += fmt.Sprintf("<button data-action='swapItems' data-path='%s' data-from='%d' data-to='%d'>up</button>", path, index, index-1)
+	}
+	if index < length-1 {
+		result += fmt.Sprintf("<button data-action='swapItems' data-path='%s' data-from='%d' data-to='%d'>down</button>", path, index, index+1)
+	}
+	return result + fmt.Sprintf("<button data-action='moveItem' data-path='%s' data-from='%d'>move</button>", path, index)
+}
+
+func mapDeleteButton(path string, key string) string {
+	return fmt.Sprintf("<button data-action='deleteEntry' data-path='%s' data-key='%s'>-</button>", path, key)
+}
+
+func mapInsertButton(path string) string {
+	return fmt.Sprintf("<input type='text' id='key-%s' placeholder='key'><button data-action='insertEntry' data-path='%s'>+</button><button data-action='clear' data-path='%s'>clear</button>", path, path, path) +
+		resetButton(path) + jsonPanel(path)
+}
+
+func intString(value string, path string) string {
+	return numberString(value, path, "-9223372036854775808", "9223372036854775807")
+}
+
+func numberString(value string, path string, min string, max string) string {
+	return fmt.Sprintf("<input type='number' id='input-%s' value='%s' min='%s' max='%s' step='1'>", path, value, min, max)
+}
+
+func boolString(value bool, path string) string {
+	if value {
+		return fmt.Sprintf("<input type='checkbox' id='input-%s' checked>", path)
+	}
+	return fmt.Sprintf("<input type='checkbox' id='input-%s'>", path)
+}
+
+func editButtons(path string) string {
+	return fmt.Sprintf("<button data-action='update' data-path='%s'>change</button>", path) + resetButton(path)
 }
 
-func primitiveEditString(value string, path string, index int) string {
-	return fmt.Sprintf("<input type='text' id='input-%d' value='%s'><button onclick=\"update('%s', 'input-%d')\">change</button>", index, value, path, index)
+func primitiveEditString(value string, path string) string {
+	return intString(value, path) + editButtons(path)
 }
 
 func TestRenderElement(t *testing.T) {
@@ -45,38 +118,81 @@ func TestRenderElement(t *testing.T) {
 		input  interface{}
 		result string
 	}{
-		{3, inputString("3", 0)},
-		{int32(5), inputString("5", 0)},
-		{uint64(10), inputString("10", 0)},
-		{3.0, inputString("3.000000", 0)},
-		{false, inputString("false", 0)},
-		{"hi", inputString("hi", 0)},
+		{3, intString("3", "")},
+		{int32(5), numberString("5", "", "-2147483648", "2147483647")},
+		{uint64(10), numberString("10", "", "0", "18446744073709551615")},
+		{3.0, "<input type='number' id='input-' value='3' step='any'>"},
+		{1e-9, "<input type='number' id='input-' value='1e-09' step='any'>"},
+		{float32(0.1), "<input type='number' id='input-' value='0.1' step='any'>"},
+		{false, boolString(false, "")},
+		{"hi", inputString("hi", "")},
+		{complex(1, -2.5), inputString("(1-2.5i)", "")},
+		{complex64(complex(0, 3)), inputString("(0+3i)", "")},
+		{90 * time.Second, inputString("1m30s", "")},
+		{*big.NewInt(-42), inputString("-42", "")},
+		{net.IPv4(10, 0, 0, 1), inputString("10.0.0.1", "")},
+		{net.IP(nil), inputString("", "")},
+		{map[userID]int{{1}: 2},
+			divString("") + "map[structeditor.userID]int {<ul><li>user-1: " +
+				intString("2", "user-1") + ",</li>}</ul></div>"},
+		{url.URL{Scheme: "https", Host: "example.com", Path: "/a b"}, inputString("https://example.com/a%20b", "")},
+		{time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC),
+			"<input type='datetime-local' id='input-' value='2019-01-02T03:04:05' step='1'>"},
 		{[3]int{1, 2, 3},
-			"<div>[3]int {<ul><li>" +
-				inputString("1", 0) +
+			divString("") + "[3]int {<ul><li>" +
+				intString("1", "0") +
 				",</li><li>" +
-				inputString("2", 1) +
+				intString("2", "1") +
 				",</li><li>" +
-				inputString("3", 2) +
+				intString("3", "2") +
 				",</li>}</ul></div>"},
 		{[]int{1, 2, 3},
-			"<div>[]int {<ul><li>" +
-				inputString("1", 0) +
+			divString("") + "[]int {<ul><li>" +
+				intString("1", "0") +
 				",</li><li>" +
-				inputString("2", 1) +
+				intString("2", "1") +
 				",</li><li>" +
-				inputString("3", 2) +
+				intString("3", "2") +
 				",</li>}</ul></div>"},
 		{&[]int{1, 2, 3},
-			"&<div>[]int {<ul><li>" +
-				primitiveEditString("1", "0", 0) +
+			"&" + divString("") + "[]int {<ul><li>" +
+				itemHandle("", 0) + primitiveEditString("1", "0") + sliceItemButtons("", 0, 3) +
 				",</li><li>" +
-				primitiveEditString("2", "1", 1) +
+				itemHandle("", 1) + primitiveEditString("2", "1") + sliceItemButtons("", 1, 3) +
 				",</li><li>" +
-				primitiveEditString("3", "2", 2) +
+				itemHandle("", 2) + primitiveEditString("3", "2") + sliceItemButtons("", 2, 3) +
 				",</li>}" + sliceEditButtons("") + "</ul></div>"},
+		{&[2]bool{true, false},
+			"&" + divString("") + "[2]bool {<ul><li>" +
+				itemHandle("", 0) + boolString(true, "0") + editButtons("0") + arrayItemButtons("", 0, 2) +
+				",</li><li>" +
+				itemHandle("", 1) + boolString(false, "1") + editButtons("1") + arrayItemButtons("", 1, 2) +
+				",</li>}" + resetButton("") + jsonPanel("") + "</ul></div>"},
 
-		{&addressableValue, "&" + primitiveEditString("5", "", 0)},
+		{&addressableValue, "&" + primitiveEditString("5", "")},
+		{map[string]int{"b": 2, "a": 1},
+			divString("") + "map[string]int {<ul><li>a: " +
+				intString("1", "a") +
+				",</li><li>b: " +
+				intString("2", "b") +
+				",</li>}</ul></div>"},
+		{map[int]bool{10: true, 9: false},
+			divString("") + "map[int]bool {<ul><li>9: " +
+				boolString(false, "9") +
+				",</li><li>10: " +
+				boolString(true, "10") +
+				",</li>}</ul></div>"},
+		{map[float64]int{2.5: 2, math.NaN(): 1},
+			divString("") + "map[float64]int {<ul><li>NaN: " +
+				intString("1", "NaN") +
+				",</li><li>2.5: " +
+				intString("2", "2%2E5") +
+				",</li>}</ul></div>"},
+		{&map[string]int{"a.b": 1},
+			"&" + divString("") + "map[string]int {<ul><li>a.b: " +
+				primitiveEditString("1", "a%2Eb") +
+				mapDeleteButton("", "a.b") +
+				",</li>}" + mapInsertButton("") + "</ul></div>"},
 	}
 
 	for _, step := range data {
@@ -94,6 +210,31 @@ func TestRenderElement(t *testing.T) {
 	}
 }
 
+type interfaceStruct struct {
+	Empty error
+	Held  interface{}
+}
+
+func TestRenderInterface(t *testing.T) {
+	testCase := interfaceStruct{
+		Held: int8(4),
+	}
+
+	e := editor{state: testCase}
+	result, err := e.unwrappedRender()
+
+	if err != nil {
+		t.Error("Rendering error:", err)
+	}
+	expected := divString("") + "interfaceStruct {<ul><li>Empty: (error) nil" +
+		",</li><li>Held: (int8) " + numberString("4", "Held", "-128", "127") +
+		",</li>}</ul></div>"
+
+	if result != expected {
+		t.Error("Expected", expected, "saw", result)
+	}
+}
+
 func TestRenderStruct(t *testing.T) {
 	testCase := exampleStruct{
 		myString: "hello",
@@ -107,13 +248,363 @@ func TestRenderStruct(t *testing.T) {
 	if err != nil {
 		t.Error("Rendering error:", err)
 	}
-	expected := "<div>exampleStruct {<ul><li>myString: " + inputString("hello", 0) +
-		",</li><li>myNumber: " + inputString("5", 1) +
-		",</li><li>myBool: " + inputString("true", 2) +
+	expected := divString("") + "exampleStruct {<ul><li class='unexported'>myString: " + inputString("hello", "myString") +
+		",</li><li class='unexported'>myNumber: " + intString("5", "myNumber") +
+		",</li><li class='unexported'>myBool: " + boolString(true, "myBool") +
+		",</li>}</ul></div>"
+
+	if result != expected {
+		t.Error("Expected", expected, "saw", result)
+	}
+
+}
+
+type mixedStruct struct {
+	Exported   int
+	unexported int
+}
+
+func TestRenderUnexported(t *testing.T) {
+	testCase := &mixedStruct{
+		Exported:   1,
+		unexported: 2,
+	}
+
+	e := editor{state: testCase}
+	result, err := e.unwrappedRender()
+	if err != nil {
+		t.Error("Rendering error:", err)
+	}
+	expected := "&" + divString("") + "mixedStruct {<ul><li>Exported: " + primitiveEditString("1", "Exported") +
+		",</li><li class='unexported'>unexported: " + intString("2", "unexported") +
+		",</li>}" + resetButton("") + jsonPanel("") + "</ul></div>"
+	if result != expected {
+		t.Error("Expected", expected, "saw", result)
+	}
+
+	e = editor{state: testCase, editUnexported: true}
+	result, err = e.unwrappedRender()
+	if err != nil {
+		t.Error("Rendering error:", err)
+	}
+	expected = "&" + divString("") + "mixedStruct {<ul><li>Exported: " + primitiveEditString("1", "Exported") +
+		",</li><li class='unexported'>unexported: " + primitiveEditString("2", "unexported") +
+		",</li>}" + resetButton("") + jsonPanel("") + "</ul></div>"
+	if result != expected {
+		t.Error("Expected", expected, "saw", result)
+	}
+}
+
+type taggedStruct struct {
+	Plain    int
+	Hidden   int    `structeditor:"-"`
+	Fixed    int    `structeditor:"readonly"`
+	Renamed  int    `structeditor:"label=Better Name"`
+	Password string `structeditor:"secret"`
+	Status   string `structeditor:"enum=open|closed"`
+}
+
+func TestRenderTags(t *testing.T) {
+	testCase := &taggedStruct{
+		Plain:    1,
+		Hidden:   2,
+		Fixed:    3,
+		Renamed:  4,
+		Password: "hunter2",
+		Status:   "closed",
+	}
+
+	e := editor{state: testCase}
+	result, err := e.unwrappedRender()
+	if err != nil {
+		t.Error("Rendering error:", err)
+	}
+	expected := "&" + divString("") + "taggedStruct {<ul><li>Plain: " + primitiveEditString("1", "Plain") +
+		",</li><li>Fixed: " + intString("3", "Fixed") +
+		",</li><li>Better Name: " + primitiveEditString("4", "Renamed") +
+		",</li><li>Password: <input type='password' id='input-Password' placeholder='secret'>" +
+		editButtons("Password") +
+		",</li><li>Status: <select id='input-Status'><option>open</option><option selected>closed</option></select>" +
+		editButtons("Status") +
+		",</li>}" + resetButton("") + jsonPanel("") + "</ul></div>"
+	if result != expected {
+		t.Error("Expected", expected, "saw", result)
+	}
+}
+
+type widgetStruct struct {
+	Notes string
+	Hue   color
+	When  time.Time
+	Ratio float32
+	Count uint8
+}
+
+func TestRenderWidgets(t *testing.T) {
+	testCase := &widgetStruct{
+		Notes: "line one\nline two",
+		Hue:   "green",
+		When:  time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC),
+		Ratio: 0.5,
+		Count: 7,
+	}
+
+	e := editor{state: testCase}
+	result, err := e.unwrappedRender()
+	if err != nil {
+		t.Error("Rendering error:", err)
+	}
+	expected := "&" + divString("") + "widgetStruct {<ul>" +
+		"<li>Notes: <textarea id='input-Notes'>line one\nline two</textarea>" + editButtons("Notes") +
+		",</li><li>Hue: <select id='input-Hue'><option>red</option><option selected>green</option><option>blue</option></select>" + editButtons("Hue") +
+		",</li><li>When: <input type='datetime-local' id='input-When' value='2019-01-02T03:04:05' step='1'>" + editButtons("When") +
+		",</li><li>Ratio: <input type='number' id='input-Ratio' value='0.5' step='any'>" + editButtons("Ratio") +
+		",</li><li>Count: " + numberString("7", "Count", "0", "255") + editButtons("Count") +
+		",</li>}" + resetButton("") + jsonPanel("") + "</ul></div>"
+	if result != expected {
+		t.Error("Expected", expected, "saw", result)
+	}
+}
+
+type hostileEnum string
+
+func (hostileEnum) EnumValues() []string {
+	return []string{"<b>", "a'b"}
+}
+
+type hostileStruct struct {
+	Choice hostileEnum
+	Text   string `structeditor:"label=<em>"`
+}
+
+func TestRenderEscaping(t *testing.T) {
+	attack := "'\"><script>alert(1)</script>"
+	escaped := "&#39;&#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;"
+
+	data := []struct {
+		input  interface{}
+		result string
+	}{
+		{attack, inputString(escaped, "")},
+		{"a\n" + attack,
+			"<textarea id='input-'>a\n" + escaped + "</textarea>"},
+		{&map[string]string{attack: "x"},
+			"&" + divString("") + "map[string]string {<ul><li>" + escaped + ": " +
+				inputString("x", escaped) + editButtons(escaped) +
+				mapDeleteButton("", escaped) +
+				",</li>}" + mapInsertButton("") + "</ul></div>"},
+		{&hostileStruct{Choice: hostileEnum(attack)},
+			"&" + divString("") + "hostileStruct {<ul><li>Choice: <select id='input-Choice'>" +
+				"<option selected>" + escaped + "</option><option>&lt;b&gt;</option><option>a&#39;b</option></select>" +
+				editButtons("Choice") +
+				",</li><li>&lt;em&gt;: " + inputString("", "Text") + editButtons("Text") +
+				",</li>}" + resetButton("") + jsonPanel("") + "</ul></div>"},
+	}
+
+	for _, step := range data {
+		e := &editor{state: step.input}
+		result, err := e.unwrappedRender()
+		if err != nil {
+			t.Error("Rendering error:", err)
+		}
+		if result != step.result {
+			t.Error("Expected", step.result, "saw", result)
+		}
+	}
+
+	page, err := NewEditor(&map[string]string{attack: attack}, "/mutate").Render()
+	if err != nil {
+		t.Error("Rendering error:", err)
+	}
+	if strings.Contains(page, "<script>alert") {
+		t.Error("Expected hostile strings to be escaped, saw", page)
+	}
+}
+
+type pointerStruct struct {
+	Set   *int
+	Unset *int
+}
+
+func TestRenderPointers(t *testing.T) {
+	value := 3
+	e := editor{state: &pointerStruct{Set: &value}}
+	result, err := e.unwrappedRender()
+	if err != nil {
+		t.Error("Rendering error:", err)
+	}
+	expected := "&" + divString("") + "pointerStruct {<ul><li>Set: &" + primitiveEditString("3", "Set") +
+		"<button data-action='clearPointer' data-path='Set'>nil</button>" +
+		",</li><li>Unset: nil<button data-action='allocate' data-path='Unset'>new</button>" + jsonPanel("Unset") +
+		",</li>}" + resetButton("") + jsonPanel("") + "</ul></div>"
+	if result != expected {
+		t.Error("Expected", expected, "saw", result)
+	}
+}
+
+type listNode struct {
+	Value int
+	Next  *listNode
+}
+
+func TestRenderCycles(t *testing.T) {
+	first := &listNode{Value: 1}
+	first.Next = &listNode{Value: 2, Next: first}
+
+	e := editor{state: first}
+	result, err := e.unwrappedRender()
+	if err != nil {
+		t.Error("Rendering error:", err)
+	}
+	expected := "&" + divString("") + "listNode {<ul><li>Value: " + primitiveEditString("1", "Value") +
+		",</li><li>Next: &" + divString("Next") + "listNode {<ul><li>Value: " + primitiveEditString("2", "Next.Value") +
+		",</li><li>Next: &<span class='alias'>(same ptr as <a href='#' data-action='reveal' data-path=''>top level</a>)</span>" +
+		"<button data-action='clearPointer' data-path='Next.Next'>nil</button>" +
+		",</li>}" + resetButton("Next") + jsonPanel("Next") + "</ul></div><button data-action='clearPointer' data-path='Next'>nil</button>" +
+		",</li>}" + resetButton("") + jsonPanel("") + "</ul></div>"
+	if result != expected {
+		t.Error("Expected", expected, "saw", result)
+	}
+}
+
+func TestRenderSharedReferences(t *testing.T) {
+	shared := map[string]int{"a": 1}
+	data := []map[string]int{shared, shared}
+
+	e := editor{state: data}
+	result, err := e.unwrappedRender()
+	if err != nil {
+		t.Error("Rendering error:", err)
+	}
+	expected := divString("") + "[]map[string]int {<ul><li>" +
+		divString("0") + "map[string]int {<ul><li>a: " + intString("1", "0.a") + ",</li>}</ul></div>" +
+		",</li><li><span class='alias'>(same map as <a href='#' data-action='reveal' data-path='0'>0</a>)</span>" +
 		",</li>}</ul></div>"
+	if result != expected {
+		t.Error("Expected", expected, "saw", result)
+	}
+}
+
+// Benchmarks rendering a slice of structs with the specified number of
+// elements, either to a string or streamed to a writer
+func benchmarkRender(b *testing.B, size int, streamed bool) {
+	data := make([]testEmployee, size)
+	for i := range data {
+		data[i] = testEmployee{Name: fmt.Sprintf("Employee %d", i), Id: fmt.Sprint(i)}
+	}
+	e := NewEditor(&data, "/mutate", WithPageSize(0))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var err error
+		if streamed {
+			err = e.RenderTo(ioutil.Discard)
+		} else {
+			_, err = e.Render()
+		}
+		if err != nil {
+			b.Fatal("Rendering error:", err)
+		}
+	}
+}
+
+func BenchmarkRender1000(b *testing.B)     { benchmarkRender(b, 1000, false) }
+func BenchmarkRender10000(b *testing.B)    { benchmarkRender(b, 10000, false) }
+func BenchmarkRender100000(b *testing.B)   { benchmarkRender(b, 100000, false) }
+func BenchmarkRenderTo1000(b *testing.B)   { benchmarkRender(b, 1000, true) }
+func BenchmarkRenderTo10000(b *testing.B)  { benchmarkRender(b, 10000, true) }
+func BenchmarkRenderTo100000(b *testing.B) { benchmarkRender(b, 100000, true) }
+
+type describedStruct struct {
+	Name string
+}
+
+func (d *describedStruct) String() string {
+	return "<" + d.Name + ">"
+}
+
+func TestRenderTextTypes(t *testing.T) {
+	testCase := &struct {
+		Owner  userID
+		Sum    checksum
+		Volume level
+		Inner  describedStruct
+	}{
+		Owner:  userID{4},
+		Sum:    checksum{0xab, 0xcd},
+		Volume: 2,
+		Inner:  describedStruct{"x"},
+	}
+
+	e := editor{state: testCase}
+	result, err := e.unwrappedRender()
+	if err != nil {
+		t.Error("Rendering error:", err)
+	}
+	expected := "&" + divString("") + " {<ul><li>Owner: " + inputString("user-4", "Owner") + editButtons("Owner") +
+		",</li><li>Sum: " + inputString("abcd", "Sum") +
+		",</li><li>Volume: " + primitiveEditString("2", "Volume") + "<span class='description'>Low</span>" +
+		",</li><li>Inner: " + divString("Inner") + "describedStruct <span class='description'>&lt;x&gt;</span> {<ul>" +
+		"<li>Name: " + inputString("x", "Inner.Name") + editButtons("Inner.Name") +
+		",</li>}" + resetButton("Inner") + jsonPanel("Inner") + "</ul></div>,</li>}" + resetButton("") + jsonPanel("") + "</ul></div>"
+	if result != expected {
+		t.Error("Expected", expected, "saw", result)
+	}
+}
+
+type token string
+
+func (t token) String() string {
+	return string(t)
+}
+
+type secretDescribed struct {
+	Token token           `structeditor:"secret"`
+	Inner describedStruct `structeditor:"secret"`
+}
 
+func TestRenderSecretDescriptions(t *testing.T) {
+	e := &editor{state: &secretDescribed{Token: "hunter2", Inner: describedStruct{"hunter3"}}}
+	result, err := e.unwrappedRender()
+	if err != nil {
+		t.Error("Rendering error:", err)
+	}
+	if strings.Contains(result, "hunter") {
+		t.Error("Expected secret descriptions to be hidden, saw", result)
+	}
+
+	tree, err := e.buildTree()
+	if err != nil {
+		t.Error("Building tree error:", err)
+	}
+	encoded, _ := json.Marshal(tree)
+	if strings.Contains(string(encoded), "hunter") {
+		t.Error("Expected secret descriptions to be hidden, saw", string(encoded))
+	}
+}
+
+type secretMapHolder struct {
+	Keys map[string]int `structeditor:"secret"`
+}
+
+func TestRenderSecretMapKeys(t *testing.T) {
+	e := &editor{state: secretMapHolder{Keys: map[string]int{"api-key-xyz": 1}}}
+	result, err := e.unwrappedRender()
+	if err != nil {
+		t.Error("Rendering error:", err)
+	}
+	expected := divString("") + "secretMapHolder {<ul><li>Keys: " + divString("Keys") +
+		"map[string]int {<ul><li class='secret'>secret (length 1)</li>}</ul></div>,</li>}</ul></div>"
 	if result != expected {
 		t.Error("Expected", expected, "saw", result)
 	}
 
+	tree, err := e.buildTree()
+	if err != nil {
+		t.Error("Building tree error:", err)
+	}
+	encoded, _ := json.Marshal(tree)
+	if strings.Contains(string(encoded), "api-key-xyz") {
+		t.Error("Expected secret map keys to be hidden, saw", string(encoded))
+	}
 }
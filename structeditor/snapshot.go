@@ -0,0 +1,269 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package structeditor
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sync"
+)
+
+// Snapshots of the state (or of the value at a path within it), which can be
+// exported from one server and imported into another to reproduce its state.
+
+// A SnapshotFormat encodes and decodes snapshots, as registered with
+// RegisterSnapshotFormat.
+type SnapshotFormat struct {
+	// Name of the format, as shown in error messages
+	Name string
+	// Content type of exported snapshots
+	ContentType string
+	// Encodes v as a snapshot
+	Encode func(w io.Writer, v interface{}) error
+	// Decodes a snapshot into v, refusing snapshots that do not match its
+	// type
+	Decode func(r io.Reader, v interface{}) error
+	// Returns true if decoding can set the value of a struct field; fields
+	// it cannot set keep their current values when a snapshot is imported.
+	// If nil, decoding is assumed to set exported fields only.
+	Sets func(sf reflect.StructField) bool
+	// Interfaces through which types decode themselves from the format.
+	// Values of those types are replaced as a whole on import.
+	Unmarshalers []reflect.Type
+}
+
+// RegisterSnapshotFormat makes a snapshot format available to every editor
+// under the specified name, for use as the "format" of ExportHandler and
+// ImportHandler. The "json" and "gob" formats are built in; registering a
+// name again replaces the format.
+func RegisterSnapshotFormat(name string, format SnapshotFormat) {
+	sets := format.Sets
+	if sets == nil {
+		sets = func(sf reflect.StructField) bool {
+			return sf.PkgPath == ""
+		}
+	}
+	snapshotFormats.Lock()
+	defer snapshotFormats.Unlock()
+	snapshotFormats.formats[name] = snapshotFormat{
+		name:         format.Name,
+		contentType:  format.ContentType,
+		encode:       format.Encode,
+		decode:       format.Decode,
+		sets:         sets,
+		unmarshalers: format.Unmarshalers,
+	}
+}
+
+// A format in which snapshots are encoded
+type snapshotFormat struct {
+	name        string
+	contentType string
+	encode      func(w io.Writer, v interface{}) error
+	// Decodes a snapshot into v, refusing snapshots that do not match its
+	// type
+	decode func(r io.Reader, v interface{}) error
+	// Returns true if the format can set the value of a struct field
+	sets func(sf reflect.StructField) bool
+	// Interfaces through which types decode themselves from the format
+	unmarshalers []reflect.Type
+}
+
+var snapshotFormats = struct {
+	sync.RWMutex
+	formats map[string]snapshotFormat
+}{formats: map[string]snapshotFormat{
+	"json": jsonFormat,
+	"gob": {
+		name:        "gob",
+		contentType: "application/octet-stream",
+		encode: func(w io.Writer, v interface{}) error {
+			return gob.NewEncoder(w).Encode(v)
+		},
+		decode: func(r io.Reader, v interface{}) error {
+			return gob.NewDecoder(r).Decode(v)
+		},
+		unmarshalers: []reflect.Type{gobDecoderType, binaryUnmarshalerType},
+		sets: func(sf reflect.StructField) bool {
+			kind := sf.Type.Kind()
+			return sf.PkgPath == "" && kind != reflect.Chan && kind != reflect.Func
+		},
+	},
+}}
+
+// The JSON snapshot format, which is also used by OperatorSetJSON
+var jsonFormat = snapshotFormat{
+	name:        "JSON",
+	contentType: "application/json",
+	encode: func(w io.Writer, v interface{}) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	},
+	decode:       decodeJSON,
+	unmarshalers: []reflect.Type{jsonUnmarshalerType, textUnmarshalerType},
+	sets: func(sf reflect.StructField) bool {
+		// The fields of an embedded struct are set even if the struct's
+		// type is unexported
+		exported := sf.PkgPath == "" || (sf.Anonymous && sf.Type.Kind() == reflect.Struct)
+		return exported && sf.Tag.Get("json") != "-"
+	},
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+var gobDecoderType = reflect.TypeOf((*gob.GobDecoder)(nil)).Elem()
+var binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+
+// decodesItself returns true if values of type t are decoded from the format
+// through their own methods.
+func (f snapshotFormat) decodesItself(t reflect.Type) bool {
+	for _, unmarshaler := range f.unmarshalers {
+		if reflect.PtrTo(t).Implements(unmarshaler) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeJSON decodes a single JSON document into v, refusing fields unknown
+// to its type.
+func decodeJSON(r io.Reader, v interface{}) error {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("Unexpected data after JSON value")
+	}
+	return nil
+}
+
+// snapshotFormatNamed returns the snapshot format registered under the
+// specified name.
+func snapshotFormatNamed(name string) (snapshotFormat, error) {
+	snapshotFormats.RLock()
+	defer snapshotFormats.RUnlock()
+	format, ok := snapshotFormats.formats[name]
+	if !ok {
+		return snapshotFormat{}, newError(ErrInvalidInput, "Unknown snapshot format '%s'", name)
+	}
+	return format, nil
+}
+
+// snapshot returns a copy of the value at path, with any secret and hidden
+// fields left as zero values. The copy can be encoded without holding the
+// state lock.
+func (e *editor) snapshot(path string) (reflect.Value, error) {
+	p, err := StringToPath(path)
+	if err != nil {
+		return reflect.Value{}, wrapError(ErrInvalidInput, err)
+	}
+	defer e.lockForRead()()
+	found, err := e.findValueToChange(p, reflect.ValueOf(e.state), false, fieldTag{})
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if found.tag.secret {
+		return reflect.Value{}, newError(ErrReadOnly, "Value at '%s' is secret", path)
+	}
+	if hasCycle(found.value) {
+		return reflect.Value{}, newError(ErrConflict, "Value at '%s' refers to itself, so cannot be exported", path)
+	}
+	return copier{copied: map[referenceKey]reflect.Value{}, omitProtected: true}.copy(found.value)
+}
+
+// ExportHandler is an HTTP request handler that returns a snapshot of the
+// state, or of the value at the path given by the "path" query parameter, in
+// the format given by the "format" query parameter: "json" (the default),
+// "gob" or one registered with RegisterSnapshotFormat. Secret and hidden
+// fields are left out of the snapshot.
+func (e *editor) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	formatName := query.Get("format")
+	if formatName == "" {
+		formatName = "json"
+	}
+	format, err := snapshotFormatNamed(formatName)
+	if err != nil {
+		http.Error(w, err.Error(), statusCode(err))
+		return
+	}
+	snapshot, err := e.snapshot(query.Get("path"))
+	if err != nil {
+		http.Error(w, err.Error(), statusCode(err))
+		return
+	}
+	// Encode the whole snapshot before sending it, so that errors can still
+	// be reported with a status code.
+	var encoded bytes.Buffer
+	err = format.encode(&encoded, snapshot.Interface())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Unable to encode snapshot as %s: %v", format.name, err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"snapshot.%s\"", formatName))
+	w.Write(encoded.Bytes())
+}
+
+// The largest snapshot ImportHandler accepts, in bytes; the same as the
+// limit on the size of forms POSTed to MutateHandler.
+const maxSnapshotSize = 10 << 20
+
+// ImportHandler is an HTTP request handler that replaces the state, or the
+// value at the path given by the "path" query parameter, with a snapshot sent
+// as the body of a POST request, in the format given by the "format" query
+// parameter (as for ExportHandler). The snapshot is applied as a mutation
+// using OperatorImport, and the response describes its outcome as for
+// MutateHandler. Snapshots larger than 10MB are refused.
+func (e *editor) ImportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Snapshots must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	formatName := query.Get("format")
+	if formatName == "" {
+		formatName = "json"
+	}
+	result := jsonMutationResult{
+		Path:     query.Get("path"),
+		Operator: "import",
+	}
+	status := http.StatusOK
+	snapshot, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxSnapshotSize))
+	if err != nil {
+		err = newError(ErrInvalidInput, "Unable to read snapshot: %v", err)
+	} else {
+		err = e.Mutate(result.Path, OperatorImport(formatName, snapshot))
+	}
+	if err != nil {
+		result.Error = err.Error()
+		status = statusCode(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,215 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package structeditor
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type snapshotState struct {
+	Turn     int
+	Started  time.Time
+	Players  []*testEmployee
+	Scores   map[string]int
+	Password string `structeditor:"secret"`
+	Internal int    `structeditor:"-"`
+	round    int
+}
+
+func newSnapshotState() *snapshotState {
+	return &snapshotState{
+		Turn:     3,
+		Started:  time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC),
+		Players:  []*testEmployee{{Name: "Alice", Id: "A"}, {Name: "Bob", Id: "B"}},
+		Scores:   map[string]int{"A": 10, "B": 7},
+		Password: "hunter2",
+		Internal: 1,
+		round:    2,
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	for _, format := range []string{"json", "gob"} {
+		source := NewEditor(newSnapshotState(), "/mutate")
+		w := httptest.NewRecorder()
+		source.ExportHandler(w, httptest.NewRequest("GET", "/export?format="+format, nil))
+		if w.Code != http.StatusOK {
+			t.Error(format, "- expected export to succeed, saw", w.Code, w.Body.String())
+			continue
+		}
+		if strings.Contains(w.Body.String(), "hunter2") {
+			t.Error(format, "- expected secret to be left out of snapshot")
+		}
+
+		replayed := &snapshotState{Password: "swordfish", Internal: 5, round: 6}
+		target := newSnapshotState()
+		target.Password, target.Internal, target.round = "swordfish", 5, 6
+		destination := NewEditor(replayed, "/mutate")
+		req := httptest.NewRequest("POST", "/import?format="+format, bytes.NewReader(w.Body.Bytes()))
+		w = httptest.NewRecorder()
+		destination.ImportHandler(w, req)
+		if w.Code != http.StatusOK {
+			t.Error(format, "- expected import to succeed, saw", w.Code, w.Body.String())
+		}
+		if !reflect.DeepEqual(replayed, target) {
+			t.Error(format, "- expected", target, "saw", replayed)
+		}
+	}
+}
+
+type snapshotAccount struct {
+	Name     string
+	Password string `structeditor:"secret"`
+	Internal int    `structeditor:"-"`
+	internal int
+}
+
+type snapshotAccounts struct {
+	Accounts []snapshotAccount
+	ByName   map[string]snapshotAccount
+}
+
+func newSnapshotAccounts() *snapshotAccounts {
+	return &snapshotAccounts{
+		Accounts: []snapshotAccount{{Name: "a", Password: "pw", Internal: 1, internal: 2}},
+		ByName:   map[string]snapshotAccount{"b": {Name: "b", Password: "pw", Internal: 3, internal: 4}},
+	}
+}
+
+func TestSnapshotContainers(t *testing.T) {
+	for _, format := range []string{"json", "gob"} {
+		data := newSnapshotAccounts()
+		e := NewEditor(data, "/mutate")
+		w := httptest.NewRecorder()
+		e.ExportHandler(w, httptest.NewRequest("GET", "/export?format="+format, nil))
+		snapshot := w.Body.Bytes()
+		w = httptest.NewRecorder()
+		e.ImportHandler(w, httptest.NewRequest("POST", "/import?format="+format, bytes.NewReader(snapshot)))
+		if w.Code != http.StatusOK || !reflect.DeepEqual(data, newSnapshotAccounts()) {
+			t.Error(format, "- expected elements to keep protected fields, saw", w.Code, w.Body.String(), data)
+		}
+	}
+
+	// New elements have their protected fields zeroed
+	data := newSnapshotAccounts()
+	e := NewEditor(data, "/mutate")
+	w := httptest.NewRecorder()
+	e.ImportHandler(w, httptest.NewRequest("POST", "/import", strings.NewReader(`{
+		"Accounts": [{"Name": "a"}, {"Name": "c", "Internal": 5}],
+		"ByName": {"b": {"Name": "b"}, "d": {"Name": "d", "Internal": 6}}}`)))
+	target := newSnapshotAccounts()
+	target.Accounts = append(target.Accounts, snapshotAccount{Name: "c"})
+	target.ByName["d"] = snapshotAccount{Name: "d"}
+	if w.Code != http.StatusOK || !reflect.DeepEqual(data, target) {
+		t.Error("Expected", target, "saw", w.Code, w.Body.String(), data)
+	}
+}
+
+func TestSnapshotSubtree(t *testing.T) {
+	data := newSnapshotState()
+	e := NewEditor(data, "/mutate")
+	w := httptest.NewRecorder()
+	e.ExportHandler(w, httptest.NewRequest("GET", "/export?path=Players.1", nil))
+	expected := "{\n  \"Name\": \"Bob\",\n  \"Id\": \"B\"\n}\n"
+	if w.Code != http.StatusOK || w.Body.String() != expected {
+		t.Error("Expected", expected, "saw", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	e.ImportHandler(w, httptest.NewRequest("POST", "/import?path=Players.0", strings.NewReader(`{"Name": "Carol", "Id": "C"}`)))
+	if w.Code != http.StatusOK || *data.Players[0] != (testEmployee{Name: "Carol", Id: "C"}) {
+		t.Error("Expected Players.0 to be replaced, saw", w.Code, w.Body.String(), data.Players[0])
+	}
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	data := newSnapshotState()
+	e := NewEditor(data, "/mutate")
+	exports := []struct {
+		query  string
+		status int
+	}{
+		{"format=xml", http.StatusBadRequest},
+		{"path=Missing", http.StatusNotFound},
+		{"path=Password", http.StatusForbidden},
+	}
+	for _, step := range exports {
+		w := httptest.NewRecorder()
+		e.ExportHandler(w, httptest.NewRequest("GET", "/export?"+step.query, nil))
+		if w.Code != step.status {
+			t.Error(step.query, "- expected status", step.status, "saw", w.Code)
+		}
+	}
+
+	imports := []struct {
+		query    string
+		snapshot string
+		status   int
+	}{
+		{"format=xml", "<x/>", http.StatusBadRequest},
+		{"format=json", `{"Turn": "four"}`, http.StatusBadRequest},
+		{"format=yaml", "turn: 4", http.StatusBadRequest},
+		{"format=gob", "not gob", http.StatusBadRequest},
+		{"path=Missing", "{}", http.StatusNotFound},
+	}
+	for _, step := range imports {
+		w := httptest.NewRecorder()
+		e.ImportHandler(w, httptest.NewRequest("POST", "/import?"+step.query, strings.NewReader(step.snapshot)))
+		if w.Code != step.status {
+			t.Error(step.query, "- expected status", step.status, "saw", w.Code)
+		}
+	}
+	if !reflect.DeepEqual(data, newSnapshotState()) {
+		t.Error("Expected failed imports to leave state unchanged, saw", data)
+	}
+
+	w := httptest.NewRecorder()
+	huge := `{"Turn": 4, "Password": "` + strings.Repeat("x", maxSnapshotSize) + `"}`
+	e.ImportHandler(w, httptest.NewRequest("POST", "/import", strings.NewReader(huge)))
+	if w.Code != http.StatusBadRequest || data.Turn != 3 {
+		t.Error("Expected oversized snapshot to be refused, saw", w.Code, data.Turn)
+	}
+
+	w = httptest.NewRecorder()
+	e.ImportHandler(w, httptest.NewRequest("GET", "/import", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Error("Expected GET to be refused, saw", w.Code)
+	}
+
+	first := &listNode{Value: 1}
+	first.Next = &listNode{Value: 2, Next: first}
+	_, err := NewEditor(first, "/mutate").(*editor).snapshot("")
+	if !errors.Is(err, ErrConflict) {
+		t.Error("Expected", ErrConflict, "saw", err)
+	}
+}
+
+func TestSnapshotNaNKeys(t *testing.T) {
+	data := map[float64]snapshotAccount{math.NaN(): {Name: "a"}, 1: {Name: "b", Internal: 2}}
+	e := NewEditor(&data, "/mutate")
+	w := httptest.NewRecorder()
+	e.ImportHandler(w, httptest.NewRequest("POST", "/import", strings.NewReader(`{"NaN": {"Name": "c"}, "1": {"Name": "d"}}`)))
+	if w.Code != http.StatusOK || len(data) != 2 || data[1] != (snapshotAccount{Name: "d", Internal: 2}) {
+		t.Error("Expected NaN entry to be replaced, saw", w.Code, w.Body.String(), data)
+	}
+}
@@ -15,6 +15,7 @@
 package structeditor
 
 import (
+	"html/template"
 	"strings"
 )
 
@@ -22,38 +23,347 @@ import (
 const STATIC_HEADER = `
 <html>
   <head>
-    <title>Struct Editor</title>
+    <title>{{.Title}}</title>
+    <style>
+      li.unexported { font-style: italic; }
+      .status { margin-left: 0.5em; }
+      .status.ok { color: green; }
+      .status.error { color: red; }
+      .aliased { outline: 1px dashed orange; }
+      .highlight { background: yellow; }
+      .page { margin: 0 0.5em; }
+      .description { margin-left: 0.5em; color: gray; }
+      .handle { cursor: move; margin-right: 0.5em; }
+      details.json { display: inline-block; vertical-align: top; }
+      details.json textarea { display: block; width: 40em; height: 10em; }
+    </style>
     <script language="javascript">
-      function sendCommand(operator, path, extraArgs) {
-        let urlParams = "?operator=" + operator +
+      // Sends a mutation to the server, with any arguments too long for a URL
+      // form-encoded in body. If it fails, the error is shown next to the
+      // source element (usually the button clicked); if it succeeds, the
+      // affected part of the page is refreshed and marked as saved.
+      function sendCommand(source, operator, path, extraArgs, body) {
+        let urlParams = "?operator=" + encodeURIComponent(operator) +
             "&path=" + encodeURIComponent(path);
         if (extraArgs) {
           urlParams += extraArgs;
         }
         let req = new XMLHttpRequest();
         req.addEventListener("load", function() {
-          // location.reload();
+          let result;
+          try {
+            result = JSON.parse(req.responseText);
+          } catch (e) {
+            result = {error: req.responseText};
+          }
+          if (req.status == 200) {
+            refresh(path, function() {
+              showStatus(findElement(path) || source, "ok", "saved");
+            });
+          } else {
+            showStatus(source, "error", result.error || req.statusText);
+          }
         });
-        // todo: listeners for errors
-        req.open("post", "${MUTATE_URL}" + urlParams);
-        req.send("");
+        req.addEventListener("error", function() {
+          showStatus(source, "error", "Unable to reach server");
+        });
+        req.open("post", {{.MutateURL}} + urlParams);
+        req.setRequestHeader("Content-Type", "application/x-www-form-urlencoded");
+        req.send(body || "");
+      }
+
+      function parentPath(path) {
+        let end = path.lastIndexOf(".");
+        return end < 0 ? "" : path.substring(0, end);
+      }
+
+      // Returns the element rendering the composite value at path.
+      function findContainer(path) {
+        return document.querySelector(
+            "div[data-path=\"" + CSS.escape(path) + "\"]");
+      }
+
+      // Returns the element to show a status next to for the value at path:
+      // the change button of a scalar, or the element rendering a composite.
+      function findElement(path) {
+        let field = document.getElementById("input-" + path);
+        if (field) {
+          return field.nextElementSibling || field;
+        }
+        return findContainer(path);
+      }
+
+      // Replaces the smallest part of the page containing path with a
+      // freshly-rendered copy, then calls done.
+      function refresh(path, done) {
+        let container = findContainer(path);
+        while (!container && path != "") {
+          path = parentPath(path);
+          container = findContainer(path);
+        }
+        if (!container) {
+          location.reload();
+          return;
+        }
+        loadFragment(container, path, container.dataset.offset, function(ok) {
+          if (!ok && path != "") {
+            // The value may no longer exist (e.g. a pointer was set to
+            // nil), so refresh whatever contains it instead.
+            refresh(parentPath(path), done);
+            return;
+          }
+          done();
+        });
+      }
+
+      // Replaces container with the value at path, as rendered by the
+      // server (starting at element offset, if it is a long array or
+      // slice), then calls done with whether the value could be rendered.
+      function loadFragment(container, path, offset, done) {
+        let url = location.pathname + "?path=" + encodeURIComponent(path);
+        if (offset) {
+          url += "&offset=" + encodeURIComponent(offset);
+        }
+        let req = new XMLHttpRequest();
+        req.addEventListener("load", function() {
+          if (req.status == 200) {
+            container.outerHTML = req.responseText;
+            markAliases();
+          }
+          done(req.status == 200);
+        });
+        req.addEventListener("error", function() {
+          done(false);
+        });
+        req.open("get", url);
+        req.send();
+      }
+
+      function showStatus(element, kind, message) {
+        let status = element.nextElementSibling;
+        if (!status || !status.classList.contains("status")) {
+          status = document.createElement("span");
+          element.insertAdjacentElement("afterend", status);
+        }
+        status.className = "status " + kind;
+        status.textContent = message;
       }
 
-      function update(path, fieldName) {
-        let newValue = document.getElementById(fieldName).value;
-        sendCommand("set", path, "&value=" + encodeURIComponent(newValue));
+      // Actions run by buttons carrying a data-action attribute. Each is
+      // passed the button and its data attributes; paths and keys are never
+      // spliced into script, so any value can be rendered safely.
+      const actions = {
+        update: function(source, data) {
+          let field = document.getElementById("input-" + data.path);
+          let newValue = field.type == "checkbox" ? String(field.checked) : field.value;
+          sendCommand(source, "set", data.path, "&value=" + encodeURIComponent(newValue));
+        },
+        grow: function(source, data) {
+          sendCommand(source, "grow", data.path);
+        },
+        shrink: function(source, data) {
+          sendCommand(source, "shrink", data.path);
+        },
+        resize: function(source, data) {
+          let length = prompt("New length");
+          if (length === null) {
+            return;
+          }
+          sendCommand(source, "resize", data.path, "&length=" + encodeURIComponent(length));
+        },
+        clear: function(source, data) {
+          sendCommand(source, "clear", data.path);
+        },
+        reset: function(source, data) {
+          sendCommand(source, "zero", data.path);
+        },
+        insertEntry: function(source, data) {
+          let key = document.getElementById("key-" + data.path).value;
+          sendCommand(source, "insert", data.path, "&key=" + encodeURIComponent(key));
+        },
+        deleteEntry: function(source, data) {
+          sendCommand(source, "delete", data.path, "&key=" + encodeURIComponent(data.key));
+        },
+        insertItem: function(source, data) {
+          sendCommand(source, "insert", data.path, "&key=" + encodeURIComponent(data.index));
+        },
+        deleteItem: function(source, data) {
+          sendCommand(source, "delete", data.path, "&key=" + encodeURIComponent(data.index));
+        },
+        duplicateItem: function(source, data) {
+          sendCommand(source, "duplicate", data.path, "&index=" + encodeURIComponent(data.index));
+        },
+        swapItems: function(source, data) {
+          sendCommand(source, "swap", data.path,
+              "&from=" + encodeURIComponent(data.from) + "&to=" + encodeURIComponent(data.to));
+        },
+        moveItem: function(source, data) {
+          let to = prompt("Move to index");
+          if (to === null) {
+            return;
+          }
+          sendCommand(source, "move", data.path,
+              "&from=" + encodeURIComponent(data.from) + "&to=" + encodeURIComponent(to));
+        },
+        allocate: function(source, data) {
+          sendCommand(source, "new", data.path);
+        },
+        clearPointer: function(source, data) {
+          sendCommand(source, "nil", data.path);
+        },
+        operator: function(source, data) {
+          let extraArgs = "";
+          let names = data.args ? data.args.split(",") : [];
+          for (let name of names) {
+            let value = prompt(name);
+            if (value === null) {
+              return;
+            }
+            extraArgs += "&" + encodeURIComponent(name) + "=" + encodeURIComponent(value);
+          }
+          sendCommand(source, data.operator, data.path, extraArgs);
+        },
+        setJSON: function(source, data) {
+          let text = document.getElementById("json-" + data.path).value;
+          sendCommand(source, "json", data.path, "", "value=" + encodeURIComponent(text));
+        },
+        expand: function(source, data) {
+          loadFragment(findContainer(data.path), data.path, "", function(ok) {
+            if (!ok) {
+              showStatus(source, "error", "Unable to load value");
+            }
+          });
+        },
+        page: function(source, data) {
+          loadFragment(findContainer(data.path), data.path, data.offset, function(ok) {
+            if (!ok) {
+              showStatus(source, "error", "Unable to load page");
+            }
+          });
+        },
+        reveal: function(source, data) {
+          let element = findValue(data.path);
+          if (element) {
+            element.scrollIntoView();
+            element.classList.add("highlight");
+            setTimeout(function() {
+              element.classList.remove("highlight");
+            }, 2000);
+          }
+        },
+      };
+
+      document.addEventListener("click", function(event) {
+        let source = event.target.closest("[data-action]");
+        if (!source || !actions.hasOwnProperty(source.dataset.action)) {
+          return;
+        }
+        event.preventDefault();
+        actions[source.dataset.action](source, source.dataset);
+      });
+
+      // Elements of arrays and slices are reordered by dragging their handles
+      // onto other elements of the same array or slice.
+      let dragged = null;
+
+      document.addEventListener("dragstart", function(event) {
+        let handle = event.target.closest && event.target.closest(".handle");
+        if (!handle) {
+          return;
+        }
+        dragged = handle;
+        event.dataTransfer.setData("text/plain", handle.dataset.index);
+        event.dataTransfer.effectAllowed = "move";
+      });
+
+      // Returns the handle of the element of the dragged element's array or
+      // slice that the event is over, if any.
+      function dropTarget(event) {
+        if (!dragged || !event.target.closest) {
+          return null;
+        }
+        let item = event.target.closest("li");
+        while (item) {
+          let handle = item.querySelector(":scope > .handle");
+          if (handle && handle.dataset.path == dragged.dataset.path) {
+            return handle;
+          }
+          item = item.parentElement && item.parentElement.closest("li");
+        }
+        return null;
       }
 
-      function grow(path) {
-        sendCommand("grow", path);
+      document.addEventListener("dragover", function(event) {
+        if (dropTarget(event)) {
+          event.preventDefault();
+        }
+      });
+
+      document.addEventListener("drop", function(event) {
+        let target = dropTarget(event);
+        if (!target) {
+          return;
+        }
+        event.preventDefault();
+        let source = dragged;
+        dragged = null;
+        if (target.dataset.index != source.dataset.index) {
+          sendCommand(source, "move", source.dataset.path,
+              "&from=" + encodeURIComponent(source.dataset.index) +
+              "&to=" + encodeURIComponent(target.dataset.index));
+        }
+      });
+
+      document.addEventListener("dragend", function() {
+        dragged = null;
+      });
+
+      // Returns the element rendering the value at path: its input if it is
+      // a scalar, or its container if it is a composite.
+      function findValue(path) {
+        return document.getElementById("input-" + path) || findContainer(path);
       }
 
-      function shrink(path) {
-        sendCommand("shrink", path);
+      // Values reachable through more than one path are rendered in full
+      // only once, with links to them elsewhere. Outline the full copies so
+      // that it is clear an edit to them also affects the other paths.
+      function markAliases() {
+        document.querySelectorAll("a[data-action=reveal]").forEach(function(link) {
+          let element = findValue(link.dataset.path);
+          if (element) {
+            element.classList.add("aliased");
+            element.title = "Shared with other values on this page";
+          }
+        });
       }
+
+      // JSON panels load the value they show when first opened.
+      document.addEventListener("toggle", function(event) {
+        let panel = event.target;
+        if (!panel.classList || !panel.classList.contains("json") || !panel.open) {
+          return;
+        }
+        let field = document.getElementById("json-" + panel.dataset.path);
+        if (field.value != "") {
+          return;
+        }
+        let req = new XMLHttpRequest();
+        req.addEventListener("load", function() {
+          if (req.status == 200) {
+            field.value = req.responseText;
+          } else {
+            showStatus(panel.querySelector("summary"), "error", req.responseText);
+          }
+        });
+        req.open("get", location.pathname + "?format=json&path=" + encodeURIComponent(panel.dataset.path));
+        req.send();
+      }, true);
+
+      document.addEventListener("DOMContentLoaded", markAliases);
     </script>
   </head>
   <body>
+    <h1>{{.Title}}</h1>
 `
 
 const STATIC_FOOTER = `
@@ -61,16 +371,38 @@ const STATIC_FOOTER = `
 </html>
 `
 
-func wrapContent(content, mutateUrl string) string {
-	return strings.Replace(
-		STATIC_HEADER,
-		"${MUTATE_URL}",
-		mutateUrl, -1) + content + STATIC_FOOTER
+// The default page template, wrapping the rendered state in STATIC_HEADER and
+// STATIC_FOOTER
+var defaultPageTemplate = template.Must(template.New("page").Parse(
+	STATIC_HEADER + "{{.Content}}" + STATIC_FOOTER))
+
+// Values available to the page template
+type pageData struct {
+	Title     string
+	MutateURL string
+	Content   template.HTML
 }
 
-// TODO:
-// adjust renderer to carry path state
-// adjust renderer to use wrapContent
-// adjust render tests to verify wrapped content
-// adjust renderer to render update buttons on paths to content
-// add mutator.go (change value of a struct element based on path)
+// Marks where the rendered state goes in the executed page template
+const contentMarker = "<!-- structeditor content -->"
+
+// pageParts executes the page template and returns the parts of the page
+// before and after the rendered state, so that the state can be written
+// between them as it is rendered. If the template does not include the
+// content, the whole page is returned as the header.
+func (e *editor) pageParts() (string, string, error) {
+	var page strings.Builder
+	err := e.pageTemplate.Execute(&page, pageData{
+		Title:     e.title,
+		MutateURL: e.mutateUrl,
+		Content:   template.HTML(contentMarker),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	parts := strings.SplitN(page.String(), contentMarker, 2)
+	if len(parts) < 2 {
+		return parts[0], "", nil
+	}
+	return parts[0], parts[1], nil
+}
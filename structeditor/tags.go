@@ -0,0 +1,91 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package structeditor
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Struct tags control how individual fields are rendered and mutated. The
+// tag is a comma-separated list of settings, for example:
+//
+//	Password string `structeditor:"secret"`
+//	Status   string `structeditor:"label=Current Status,enum=open|closed"`
+//
+// The recognized settings are:
+//
+//	"-"        the field is neither rendered nor mutable
+//	readonly   the field (and everything inside it) is rendered but not mutable
+//	label=...  the field is rendered with the specified name
+//	secret     the field's value (and everything inside it) is not rendered
+//	enum=a|b   the field can only be set to one of the listed values
+const tagName = "structeditor"
+
+// Settings parsed from a struct tag
+type fieldTag struct {
+	hidden   bool
+	readOnly bool
+	label    string
+	secret   bool
+	enum     []string
+}
+
+func parseFieldTag(sf reflect.StructField) fieldTag {
+	var tag fieldTag
+	value, ok := sf.Tag.Lookup(tagName)
+	if !ok {
+		return tag
+	}
+	if value == "-" {
+		tag.hidden = true
+		return tag
+	}
+	for _, setting := range strings.Split(value, ",") {
+		switch {
+		case setting == "readonly":
+			tag.readOnly = true
+		case setting == "secret":
+			tag.secret = true
+		case strings.HasPrefix(setting, "label="):
+			tag.label = strings.TrimPrefix(setting, "label=")
+		case strings.HasPrefix(setting, "enum="):
+			tag.enum = strings.Split(strings.TrimPrefix(setting, "enum="), "|")
+		}
+	}
+	return tag
+}
+
+// enclosing returns the settings for a field nested inside a field with
+// these settings: read-only and secret fields make everything inside them
+// read-only and secret.
+func (t fieldTag) enclosing(field fieldTag) fieldTag {
+	field.readOnly = field.readOnly || t.readOnly
+	field.secret = field.secret || t.secret
+	return field
+}
+
+// allows returns true if the field can be set to the specified value.
+func (t fieldTag) allows(value string) bool {
+	if len(t.enum) == 0 {
+		return true
+	}
+	for _, allowed := range t.enum {
+		if value == allowed {
+			return true
+		}
+	}
+	return false
+}
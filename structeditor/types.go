@@ -0,0 +1,288 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package structeditor
+
+import (
+	"encoding"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"reflect"
+	"time"
+	"unsafe"
+)
+
+// Handling for specific types that are not rendered or mutated according to
+// their kind alone
+
+// Enumerable is implemented by types that can only take on a fixed set of
+// values, such as named string types used as enums. Values of these types are
+// rendered as a select list and can only be set to one of the listed values.
+// EnumValues should return the same list regardless of the receiver's value
+// (it is called on the zero value of the type).
+type Enumerable interface {
+	EnumValues() []string
+}
+
+var enumerableType = reflect.TypeOf((*Enumerable)(nil)).Elem()
+
+// allowedValues returns the values a scalar may be set to, either from its
+// struct tag or from its type implementing Enumerable; nil if any value is
+// allowed.
+func allowedValues(v reflect.Value, tag fieldTag) []string {
+	if len(tag.enum) > 0 {
+		return tag.enum
+	}
+	if reflect.PtrTo(v.Type()).Implements(enumerableType) {
+		return reflect.New(v.Type()).Interface().(Enumerable).EnumValues()
+	}
+	return nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Layout of the value of an HTML datetime-local input
+const datetimeLocalLayout = "2006-01-02T15:04:05"
+
+// parseTime parses a time in RFC 3339 format, or in the format used by HTML
+// datetime-local inputs (with or without seconds). Times without a time zone
+// are interpreted in the specified location.
+func parseTime(s string, loc *time.Location) (time.Time, error) {
+	result, err := time.Parse(time.RFC3339Nano, s)
+	if err == nil {
+		return result, nil
+	}
+	for _, layout := range []string{datetimeLocalLayout, "2006-01-02T15:04"} {
+		result, layoutErr := time.ParseInLocation(layout, s, loc)
+		if layoutErr == nil {
+			return result, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// Formatting and parsing for standard library types that are edited as a
+// single value, rather than through their fields or according to their kind
+type wellKnownType struct {
+	// Returns the string form of a value of the type
+	format func(value interface{}) string
+	// Parses the string form of a value of the type; current is the value
+	// it will replace.
+	parse func(s string, current interface{}) (interface{}, error)
+}
+
+var wellKnownTypes = map[reflect.Type]wellKnownType{
+	timeType: {
+		format: func(value interface{}) string {
+			return value.(time.Time).Format(time.RFC3339Nano)
+		},
+		parse: func(s string, current interface{}) (interface{}, error) {
+			return parseTime(s, current.(time.Time).Location())
+		},
+	},
+	reflect.TypeOf(time.Duration(0)): {
+		format: func(value interface{}) string {
+			return value.(time.Duration).String()
+		},
+		parse: func(s string, current interface{}) (interface{}, error) {
+			return time.ParseDuration(s)
+		},
+	},
+	reflect.TypeOf(big.Int{}): {
+		format: func(value interface{}) string {
+			n := value.(big.Int)
+			return n.String()
+		},
+		parse: func(s string, current interface{}) (interface{}, error) {
+			n, ok := new(big.Int).SetString(s, 0)
+			if !ok {
+				return nil, fmt.Errorf("Unable to parse '%s' as an integer", s)
+			}
+			return *n, nil
+		},
+	},
+	reflect.TypeOf(net.IP{}): {
+		format: func(value interface{}) string {
+			ip := value.(net.IP)
+			if len(ip) == 0 {
+				return ""
+			}
+			return ip.String()
+		},
+		parse: func(s string, current interface{}) (interface{}, error) {
+			if s == "" {
+				return net.IP(nil), nil
+			}
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("Unable to parse '%s' as an IP address", s)
+			}
+			return ip, nil
+		},
+	},
+	reflect.TypeOf(url.URL{}): {
+		format: func(value interface{}) string {
+			u := value.(url.URL)
+			return u.String()
+		},
+		parse: func(s string, current interface{}) (interface{}, error) {
+			u, err := url.Parse(s)
+			if err != nil {
+				return nil, err
+			}
+			return *u, nil
+		},
+	},
+}
+
+// readableValue returns v, or if v was obtained through unexported fields, an
+// equivalent value whose contents can be read with Interface. Such values can
+// only be read if they are addressable; returns false if v cannot be read.
+func readableValue(v reflect.Value) (reflect.Value, bool) {
+	if v.CanInterface() {
+		return v, true
+	}
+	if !v.CanAddr() {
+		return reflect.Value{}, false
+	}
+	return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem(), true
+}
+
+// interfaceOf returns the value held by v as an interface{}. Returns false if
+// the value cannot be read (see readableValue).
+func interfaceOf(v reflect.Value) (interface{}, bool) {
+	readable, ok := readableValue(v)
+	if !ok {
+		return nil, false
+	}
+	return readable.Interface(), true
+}
+
+// formatWellKnown returns the string form of a value of a well-known type.
+// Returns false if the value is not of a well-known type or cannot be read.
+func formatWellKnown(v reflect.Value) (string, bool) {
+	wellKnown, ok := wellKnownTypes[v.Type()]
+	if !ok {
+		return "", false
+	}
+	value, ok := interfaceOf(v)
+	if !ok {
+		return "", false
+	}
+	return wellKnown.format(value), true
+}
+
+// setWellKnown parses the string form of a value of a well-known type and
+// sets v to it.
+func setWellKnown(v reflect.Value, s string) error {
+	current, ok := interfaceOf(v)
+	if !ok {
+		current = reflect.Zero(v.Type()).Interface()
+	}
+	newValue, err := wellKnownTypes[v.Type()].parse(s, current)
+	if err != nil {
+		return wrapError(ErrInvalidInput, err)
+	}
+	v.Set(reflect.ValueOf(newValue))
+	return nil
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// implements returns true if values of type t have the methods of iface,
+// either directly or through a pointer to them. Pointers and interfaces are
+// never considered to implement iface, as they may be nil; the values they
+// refer to are considered instead.
+func implements(t reflect.Type, iface reflect.Type) bool {
+	if t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface {
+		return false
+	}
+	return t.Implements(iface) || reflect.PtrTo(t).Implements(iface)
+}
+
+// methodsOf returns v, or a pointer to v, as an interface{} that has the
+// methods of iface. If the methods need a pointer receiver and v is not
+// addressable, the pointer is to a copy of v. Returns false if v does not
+// implement iface or cannot be read.
+func methodsOf(v reflect.Value, iface reflect.Type) (interface{}, bool) {
+	if !implements(v.Type(), iface) {
+		return nil, false
+	}
+	if v.Type().Implements(iface) {
+		return interfaceOf(v)
+	}
+	if v.CanAddr() {
+		return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Interface(), true
+	}
+	value, ok := interfaceOf(v)
+	if !ok {
+		return nil, false
+	}
+	copied := reflect.New(v.Type())
+	copied.Elem().Set(reflect.ValueOf(value))
+	return copied.Interface(), true
+}
+
+// formatText returns the text form of a value whose type implements
+// encoding.TextMarshaler. Returns false if it does not, or if the value
+// cannot be read or marshaled.
+func formatText(v reflect.Value) (string, bool) {
+	marshaler, ok := methodsOf(v, textMarshalerType)
+	if !ok {
+		return "", false
+	}
+	text, err := marshaler.(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		return "", false
+	}
+	return string(text), true
+}
+
+// marshalsTextOnly returns true if values of type t are shown in their text
+// form but cannot be parsed from it, and so cannot be set.
+func marshalsTextOnly(t reflect.Type) bool {
+	return implements(t, textMarshalerType) && !implements(t, textUnmarshalerType)
+}
+
+// setText parses the text form of a value whose type implements
+// encoding.TextUnmarshaler and sets v to it. v must be addressable.
+func setText(v reflect.Value, s string) error {
+	unmarshaler := v.Addr().Interface().(encoding.TextUnmarshaler)
+	err := unmarshaler.UnmarshalText([]byte(s))
+	if err != nil {
+		return wrapError(ErrInvalidInput, err)
+	}
+	return nil
+}
+
+// describe returns the result of calling String on a value whose type
+// implements fmt.Stringer but is not shown in its text form. Returns false
+// if it does not, or if the value cannot be read.
+func describe(v reflect.Value) (string, bool) {
+	if implements(v.Type(), textMarshalerType) {
+		return "", false
+	}
+	if _, ok := wellKnownTypes[v.Type()]; ok {
+		return "", false
+	}
+	stringer, ok := methodsOf(v, stringerType)
+	if !ok {
+		return "", false
+	}
+	return stringer.(fmt.Stringer).String(), true
+}
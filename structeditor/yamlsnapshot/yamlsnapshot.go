@@ -0,0 +1,55 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package yamlsnapshot registers a "yaml" snapshot format with structeditor,
+// encoding snapshots with gopkg.in/yaml.v3. Import it for its side effect:
+//
+//	import _ "github.com/google/go-structeditor/structeditor/yamlsnapshot"
+package yamlsnapshot
+
+import (
+	"encoding"
+	"io"
+	"reflect"
+
+	"github.com/google/go-structeditor/structeditor"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	structeditor.RegisterSnapshotFormat("yaml", structeditor.SnapshotFormat{
+		Name:        "YAML",
+		ContentType: "application/yaml",
+		Encode: func(w io.Writer, v interface{}) error {
+			encoder := yaml.NewEncoder(w)
+			err := encoder.Encode(v)
+			if err != nil {
+				return err
+			}
+			return encoder.Close()
+		},
+		Decode: func(r io.Reader, v interface{}) error {
+			decoder := yaml.NewDecoder(r)
+			decoder.KnownFields(true)
+			return decoder.Decode(v)
+		},
+		Sets: func(sf reflect.StructField) bool {
+			return sf.PkgPath == "" && sf.Tag.Get("yaml") != "-"
+		},
+		Unmarshalers: []reflect.Type{
+			reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem(),
+			reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem(),
+		},
+	})
+}
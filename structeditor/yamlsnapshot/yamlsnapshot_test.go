@@ -0,0 +1,68 @@
+// Copyright 2019 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yamlsnapshot
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-structeditor/structeditor"
+)
+
+type player struct {
+	Name     string
+	Password string `structeditor:"secret"`
+}
+
+type state struct {
+	Turn    int
+	Started time.Time
+	Players []player
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	data := &state{
+		Turn:    3,
+		Started: time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC),
+		Players: []player{{Name: "Alice", Password: "hunter2"}},
+	}
+	e := structeditor.NewEditor(data, "/mutate")
+	w := httptest.NewRecorder()
+	e.ExportHandler(w, httptest.NewRequest("GET", "/export?format=yaml&path=Players.0", nil))
+	expected := "name: Alice\npassword: \"\"\n"
+	if w.Code != http.StatusOK || w.Body.String() != expected {
+		t.Error("Expected", expected, "saw", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	e.ExportHandler(w, httptest.NewRequest("GET", "/export?format=yaml", nil))
+	snapshot := strings.Replace(w.Body.String(), "turn: 3", "turn: 4", 1)
+	w = httptest.NewRecorder()
+	e.ImportHandler(w, httptest.NewRequest("POST", "/import?format=yaml", bytes.NewReader([]byte(snapshot))))
+	if w.Code != http.StatusOK || data.Turn != 4 || !data.Started.Equal(time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)) ||
+		data.Players[0] != (player{Name: "Alice", Password: "hunter2"}) {
+		t.Error("Expected Turn to be imported, saw", w.Code, w.Body.String(), data)
+	}
+
+	w = httptest.NewRecorder()
+	e.ImportHandler(w, httptest.NewRequest("POST", "/import?format=yaml", strings.NewReader("unknown: 1")))
+	if w.Code != http.StatusBadRequest {
+		t.Error("Expected unknown fields to be refused, saw", w.Code, w.Body.String())
+	}
+}